@@ -0,0 +1,117 @@
+package bifrost
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// DefaultAuditContentTypes are the bodies an audit logger logs when its
+// options list none, those RedactFields can redact.
+var DefaultAuditContentTypes = []string{MIMEApplicationJSON, MIMEApplicationForm}
+
+// AuditOpts configures the middleware returned by NewAuditLogger.
+type AuditOpts struct {
+	// SampleRate is the fraction of the requests logged, all of them when 0.
+	SampleRate float64
+	// MaxBodySize is the number of bytes of the body logged, 4096 by
+	// default. Larger bodies are not logged, only reported as truncated.
+	MaxBodySize int
+	// ContentTypes are the media types whose bodies are logged, the
+	// DefaultAuditContentTypes when empty.
+	ContentTypes []string
+	// Redact hides the sensitive values of the bodies, the
+	// DefaultRedactedFields are redacted when nil.
+	Redact BodyRedactor
+}
+
+func (o AuditOpts) sampled() bool {
+	return o.SampleRate <= 0 || o.SampleRate >= 1 || rand.Float64() < o.SampleRate
+}
+
+func (o AuditOpts) allowed(contentType string) bool {
+	types := o.ContentTypes
+	if len(types) < 1 {
+		types = DefaultAuditContentTypes
+	}
+	for _, t := range types {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAuditLogger returns a middleware logging the bodies of the requests
+// along with their outcome. The body is peeked at and replayed to the next
+// handler, which sees it unchanged.
+func NewAuditLogger(opts AuditOpts) func(next http.Handler) http.Handler {
+	if opts.MaxBodySize < 1 {
+		opts.MaxBodySize = 4096
+	}
+	if opts.Redact == nil {
+		opts.Redact = RedactFields(DefaultRedactedFields...)
+	}
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if !opts.sampled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			cType := r.Header.Get(HeaderContentType)
+			var body []byte
+			truncated := false
+			if r.ContentLength != 0 && opts.allowed(cType) {
+				body, truncated = peekBody(r, opts.MaxBodySize)
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			event := GetLogger(r.Context()).Info().
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str(HeaderContentType, cType).
+				Int("status", ww.Status()).
+				Dur("duration", time.Since(start))
+			if truncated {
+				event = event.Bool("body_truncated", true)
+			} else if len(body) > 0 {
+				event = event.Str("body", string(opts.Redact(cType, body)))
+			}
+			event.Msg("request audit")
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// peekBody reads up to limit bytes of the body of r and puts them back in
+// front of the rest of it. Bodies larger than limit are reported as
+// truncated and nil is returned, since a redactor cannot parse a part of one.
+func peekBody(r *http.Request, limit int) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false
+	}
+	buf := make([]byte, limit+1)
+	n, err := io.ReadFull(r.Body, buf)
+	buf = buf[:n]
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, false
+	}
+	if n > limit {
+		return nil, true
+	}
+	return buf, false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
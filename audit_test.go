@@ -0,0 +1,92 @@
+package bifrost
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func auditLines(t *testing.T, opts AuditOpts, requests ...*http.Request) []map[string]interface{} {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	handler := NewAuditLogger(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		_, _ = w.Write(b)
+	}))
+	for _, r := range requests {
+		w := httptest.NewRecorder()
+		body := ""
+		if r.Body != nil {
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+			r.Body = ioutil.NopCloser(strings.NewReader(body))
+		}
+		handler.ServeHTTP(w, r.WithContext(WithLogger(r.Context(), logger)))
+		assert.Equal(t, body, w.Body.String())
+	}
+
+	lines := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		m := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal([]byte(line), &m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func auditRequest(contentType string, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	r.Header.Set(HeaderContentType, contentType)
+	return r
+}
+
+func TestAuditLogger(t *testing.T) {
+	lines := auditLines(t, AuditOpts{MaxBodySize: 40},
+		auditRequest(MIMEApplicationJSON, `{"user":"gopher","password":"hunter2"}`),
+		auditRequest(MIMEApplicationJSON, `{"user":"gopher","note":"leave it at the front door"}`),
+		auditRequest(MIMEOctetStream, `binary`),
+		auditRequest(MIMETextPlain, `password=hunter2`),
+	)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, `{"password":"[REDACTED]","user":"gopher"}`, lines[0]["body"])
+	assert.Equal(t, float64(http.StatusOK), lines[0]["status"])
+	assert.Equal(t, "/orders", lines[0]["path"])
+	assert.NotContains(t, lines[1], "body")
+	assert.Equal(t, true, lines[1]["body_truncated"])
+	assert.NotContains(t, lines[2], "body")
+	assert.NotContains(t, lines[3], "body")
+}
+
+func TestAuditLoggerContentTypes(t *testing.T) {
+	lines := auditLines(t, AuditOpts{ContentTypes: []string{MIMETextPlain}, Redact: func(string, []byte) []byte {
+		return []byte("redacted")
+	}},
+		auditRequest(MIMETextPlainCharsetUTF8, `hello`),
+		auditRequest(MIMEApplicationJSON, `{}`),
+	)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "redacted", lines[0]["body"])
+	assert.NotContains(t, lines[1], "body")
+}
+
+func TestAuditLoggerSampling(t *testing.T) {
+	requests := make([]*http.Request, 0, 200)
+	for n := 0; n < 200; n++ {
+		requests = append(requests, auditRequest(MIMEApplicationJSON, `{}`))
+	}
+	lines := auditLines(t, AuditOpts{SampleRate: 0.25}, requests...)
+	assert.Greater(t, len(lines), 10)
+	assert.Less(t, len(lines), 110)
+}
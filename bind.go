@@ -2,17 +2,198 @@ package bifrost
 
 import (
 	"fmt"
+	"github.com/go-chi/chi/v5"
 	"github.com/monoculum/formam"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
+	"time"
 )
 
+// Request sources read by Bind, each one is also the struct tag naming the
+// parameter to read.
+const (
+	BindPath   = "path"
+	BindQuery  = "query"
+	BindHeader = "header"
+	BindCookie = "cookie"
+)
+
+// ErrBindParameter is the error answered for a parameter Bind cannot convert.
+var ErrBindParameter = NewError(http.StatusBadRequest, "invalid_parameter", "invalid request parameter")
+
+// BindBody decodes the json or form body of r into i, then checks the
+// `validate` tags of i. A body failing to decode is an ErrBindParameter,
+// validation failures are returned as ValidationErrors.
 func BindBody(r *http.Request, i interface{}) error {
-	if r.ContentLength < 1 {
+	if !hasBody(r) {
 		return http.ErrContentLength
 	}
+	if err := bindBody(r, i); err != nil {
+		return bodyError(err)
+	}
+	return Validate(i)
+}
+
+// Bind fills the fields of i from the chi url parameters, query string,
+// headers and cookies of r named by their `path`, `query`, `header` and
+// `cookie` tags, after decoding the body, if any, as BindBody does. Fields of
+// embedded structs are bound too, and keys are matched as they are, such as
+// "filter[status]". Values are converted to the type of the field, numbers,
+// bools, time.Time (RFC 3339 or 2006-01-02), time.Duration, slices and
+// pointers included.
+// A conversion failure is an ErrBindParameter naming the source and field,
+// as is a body failing to decode, then the `validate` tags of i are checked.
+//
+//	type listOrders struct {
+//		ShopID int           `path:"shop_id"`
+//		Status []string      `query:"status"`
+//		Since  *time.Time    `query:"since"`
+//		Tenant string        `header:"X-Tenant-Id"`
+//		Wait   time.Duration `query:"wait"`
+//	}
+func Bind(r *http.Request, i interface{}) error {
+	if r.Method != http.MethodGet && hasBody(r) {
+		if err := bindBody(r, i); err != nil {
+			return bodyError(err)
+		}
+	}
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind destination must be a pointer to a struct, got %T", i)
+	}
+	for _, source := range []string{BindPath, BindQuery, BindHeader, BindCookie} {
+		if _, err := bindParams(r, v.Elem(), source); err != nil {
+			return err
+		}
+	}
+	return Validate(i)
+}
+
+// hasBody tells whether r has a body, of a declared length or not, as a
+// chunked or HTTP/2 one.
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+func bodyError(err error) error {
+	return ErrBindParameter.WithDetails(ErrorDetail{
+		Code:    "body",
+		Message: "invalid request body",
+	}).Wrap(err)
+}
+
+// bindParams sets the fields of v, embedded structs included, from the
+// values of r of source named by their tag. Keys are taken as they are,
+// brackets and dots included.
+func bindParams(r *http.Request, v reflect.Value, source string) (bool, error) {
+	bound := false
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		f, field := t.Field(n), v.Field(n)
+		key := f.Tag.Get(source)
+		if key == "" && f.Anonymous {
+			ok, err := bindEmbedded(r, field, source)
+			if err != nil {
+				return false, err
+			}
+			bound = bound || ok
+			continue
+		}
+		if key == "" || key == "-" || !field.CanSet() {
+			continue
+		}
+		values := paramValues(r, source, key)
+		if len(values) < 1 {
+			continue
+		}
+		if err := decodeParam(field, values); err != nil {
+			return false, ErrBindParameter.WithDetails(ErrorDetail{
+				Field:   key,
+				Code:    source,
+				Message: fmt.Sprintf("invalid %s parameter %q", source, strings.Join(values, ",")),
+			}).Wrap(err)
+		}
+		bound = true
+	}
+	return bound, nil
+}
 
+// bindEmbedded binds the fields of an embedded struct, allocating a nil
+// pointer to one only when a value is bound.
+func bindEmbedded(r *http.Request, field reflect.Value, source string) (bool, error) {
+	switch {
+	case field.Kind() == reflect.Struct:
+		return bindParams(r, field, source)
+	case field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct:
+		if !field.IsNil() {
+			return bindParams(r, field.Elem(), source)
+		}
+		if !field.CanSet() {
+			return false, nil
+		}
+		elem := reflect.New(field.Type().Elem())
+		bound, err := bindParams(r, elem.Elem(), source)
+		if bound && err == nil {
+			field.Set(elem)
+		}
+		return bound, err
+	}
+	return false, nil
+}
+
+// decodeParam converts values to the type of field through formam, under a
+// plain key which it cannot mistake for a nested one.
+func decodeParam(field reflect.Value, values []string) error {
+	holder := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: field.Type(),
+		Tag:  `param:"value"`,
+	}}))
+	if err := paramDecoder.Decode(url.Values{"value": values}, holder.Interface()); err != nil {
+		return err
+	}
+	field.Set(holder.Elem().Field(0))
+	return nil
+}
+
+func paramValues(r *http.Request, source string, key string) []string {
+	switch source {
+	case BindPath:
+		if value := chi.URLParam(r, key); value != "" {
+			return []string{value}
+		}
+	case BindQuery:
+		return r.URL.Query()[key]
+	case BindHeader:
+		return r.Header.Values(key)
+	case BindCookie:
+		if c, err := r.Cookie(key); err == nil {
+			return []string{c.Value}
+		}
+	}
+	return nil
+}
+
+var paramDecoder = newParamDecoder()
+
+func newParamDecoder() *formam.Decoder {
+	dec := formam.NewDecoder(&formam.DecoderOptions{TagName: "param"})
+	dec.RegisterCustomType(func(vals []string) (interface{}, error) {
+		if t, err := time.Parse(time.RFC3339, vals[0]); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02", vals[0])
+	}, []interface{}{time.Time{}}, nil)
+	dec.RegisterCustomType(func(vals []string) (interface{}, error) {
+		return time.ParseDuration(vals[0])
+	}, []interface{}{time.Duration(0)}, nil)
+	return dec
+}
+
+func bindBody(r *http.Request, i interface{}) error {
 	cType := r.Header.Get(HeaderContentType)
 	switch {
 	case strings.HasPrefix(cType, MIMEApplicationJSON):
@@ -28,7 +209,15 @@ func BindBody(r *http.Request, i interface{}) error {
 		)
 		return dec.Decode(p, i)
 	default:
-		return fmt.Errorf("not allowed %s: %q", HeaderContentType, cType)
+		codec, ok := GetCodec(cType)
+		if !ok {
+			return fmt.Errorf("not allowed %s: %q", HeaderContentType, cType)
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		return codec.Unmarshal(b, i)
 	}
 }
 
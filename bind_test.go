@@ -3,11 +3,15 @@ package bifrost
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type person struct {
@@ -74,3 +78,157 @@ func TestBindBodyFailContentType(t *testing.T) {
 	err = BindBody(r, &pn)
 	assert.Error(t, err)
 }
+
+type listOrders struct {
+	ShopID   int           `path:"shop_id"`
+	Status   []string      `query:"status"`
+	Page     *int          `query:"page"`
+	Archived bool          `query:"archived"`
+	Since    time.Time     `query:"since"`
+	Until    *time.Time    `query:"until"`
+	Wait     time.Duration `query:"wait"`
+	Tenant   string        `header:"X-Tenant-Id" validate:"required"`
+	Session  string        `cookie:"session"`
+	Note     string        `json:"note"`
+}
+
+func serveBind(t *testing.T, r *http.Request) (listOrders, error) {
+	t.Helper()
+	var (
+		dst listOrders
+		err error
+	)
+	router := chi.NewRouter()
+	router.HandleFunc("/shops/{shop_id}/orders", func(w http.ResponseWriter, r *http.Request) {
+		err = Bind(r, &dst)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), r)
+	return dst, err
+}
+
+func TestBindParams(t *testing.T) {
+	r, err := http.NewRequest(http.MethodPost,
+		"/shops/7/orders?status=paid&status=shipped&page=2&archived=true&since=2021-12-29&until=2021-12-31T10:00:00Z&wait=1m30s",
+		bytes.NewBufferString(`{"note":"urgent"}`))
+	assert.NoError(t, err)
+	r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	r.Header.Set("X-Tenant-Id", "warung")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+
+	dst, err := serveBind(t, r)
+	assert.NoError(t, err)
+	page := 2
+	until := time.Date(2021, 12, 31, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, listOrders{
+		ShopID:   7,
+		Status:   []string{"paid", "shipped"},
+		Page:     &page,
+		Archived: true,
+		Since:    time.Date(2021, 12, 29, 0, 0, 0, 0, time.UTC),
+		Until:    &until,
+		Wait:     90 * time.Second,
+		Tenant:   "warung",
+		Session:  "s3cr3t",
+		Note:     "urgent",
+	}, dst)
+}
+
+func TestBindParamsGet(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/shops/7/orders", nil)
+	assert.NoError(t, err)
+	r.Header.Set("X-Tenant-Id", "warung")
+
+	dst, err := serveBind(t, r)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, dst.ShopID)
+	assert.Nil(t, dst.Page)
+}
+
+func TestBindParamsInvalid(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/shops/7/orders?page=two", nil)
+	assert.NoError(t, err)
+	r.Header.Set("X-Tenant-Id", "warung")
+
+	_, err = serveBind(t, r)
+	assert.True(t, errors.Is(err, ErrBindParameter))
+	e := AsError(err)
+	assert.Equal(t, http.StatusBadRequest, e.StatusCode())
+	assert.Equal(t, []ErrorDetail{{Field: "page", Code: BindQuery, Message: `invalid query parameter "two"`}}, e.Details)
+}
+
+func TestBindParamsValidation(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/shops/7/orders", nil)
+	assert.NoError(t, err)
+
+	_, err = serveBind(t, r)
+	var verrs ValidationErrors
+	assert.True(t, errors.As(err, &verrs))
+	assert.Equal(t, "X-Tenant-Id", verrs[0].Field)
+}
+
+func TestBindParamsChunkedBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/shops/7/orders", bytes.NewBufferString(`{"note":"urgent"}`))
+	r.ContentLength = -1
+	r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	r.Header.Set("X-Tenant-Id", "warung")
+
+	dst, err := serveBind(t, r)
+	assert.NoError(t, err)
+	assert.Equal(t, "urgent", dst.Note)
+
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"name":"surya"}`))
+	r.ContentLength = -1
+	r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	var p person
+	assert.NoError(t, BindBody(r, &p))
+	assert.Equal(t, "surya", p.Name)
+}
+
+func TestBindParamsMalformedBody(t *testing.T) {
+	r, err := http.NewRequest(http.MethodPost, "/shops/7/orders", bytes.NewBufferString(`{"note":`))
+	assert.NoError(t, err)
+	r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	r.Header.Set("X-Tenant-Id", "warung")
+
+	_, err = serveBind(t, r)
+	assert.True(t, errors.Is(err, ErrBindParameter))
+	e := AsError(err)
+	assert.Equal(t, http.StatusBadRequest, e.StatusCode())
+	assert.Equal(t, []ErrorDetail{{Code: "body", Message: "invalid request body"}}, e.Details)
+}
+
+type orderFilter struct {
+	Status string `query:"filter[status]"`
+	Shop   int    `query:"shop.id"`
+}
+
+// Paging is exported, reflect cannot allocate an unexported embedded pointer.
+type Paging struct {
+	Limit int `query:"limit"`
+}
+
+type searchOrders struct {
+	orderFilter
+	*Paging
+	Tenant string `header:"X-Tenant-Id"`
+}
+
+func TestBindParamsKeys(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/orders?filter[status]=paid&shop.id=7&limit=20", nil)
+	r.Header.Set("X-Tenant-Id", "warung")
+	var dst searchOrders
+	assert.NoError(t, Bind(r, &dst))
+	assert.Equal(t, "paid", dst.Status)
+	assert.Equal(t, 7, dst.Shop)
+	if assert.NotNil(t, dst.Paging) {
+		assert.Equal(t, 20, dst.Limit)
+	}
+	assert.Equal(t, "warung", dst.Tenant)
+
+	r = httptest.NewRequest(http.MethodGet, "/orders?shop.id=seven", nil)
+	dst = searchOrders{}
+	err := Bind(r, &dst)
+	assert.True(t, errors.Is(err, ErrBindParameter))
+	assert.Equal(t, []ErrorDetail{{Field: "shop.id", Code: BindQuery, Message: `invalid query parameter "seven"`}}, AsError(err).Details)
+	assert.Nil(t, dst.Paging)
+}
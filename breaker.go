@@ -0,0 +1,218 @@
+package bifrost
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned for the requests to a host whose circuit is open.
+var ErrCircuitOpen = errors.New("bifrost: circuit open")
+
+// BreakerState is the state of the circuit of a host.
+type BreakerState int
+
+const (
+	// BreakerClosed lets the requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails the requests with ErrCircuitOpen.
+	BreakerOpen
+	// BreakerHalfOpen lets a few probing requests through, whose outcome
+	// closes or opens the circuit again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerOpts configures the circuit breaker returned by NewCircuitBreaker.
+type BreakerOpts struct {
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit, 5 by default.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before probing the
+	// host, 30 seconds by default.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probes let through when half-open,
+	// which all have to succeed to close the circuit, 1 by default.
+	HalfOpenRequests int
+	// IsFailure tells whether an outcome counts as a failure, by default
+	// the transport errors, cancellations aside, and the 5xx statuses.
+	IsFailure func(res *http.Response, err error) bool
+	// OnStateChange is called when the circuit of a host changes state.
+	OnStateChange func(host string, from, to BreakerState)
+}
+
+func (o BreakerOpts) withDefaults() BreakerOpts {
+	if o.FailureThreshold < 1 {
+		o.FailureThreshold = 5
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = 30 * time.Second
+	}
+	if o.HalfOpenRequests < 1 {
+		o.HalfOpenRequests = 1
+	}
+	if o.IsFailure == nil {
+		o.IsFailure = func(res *http.Response, err error) bool {
+			if err != nil {
+				return DefaultRetryOn(nil, err)
+			}
+			return res.StatusCode >= http.StatusInternalServerError
+		}
+	}
+	return o
+}
+
+// CircuitBreaker is a round-tripper keeping a circuit per host, which opens
+// after consecutive failures and fails the requests fast until a probe
+// finds the host healthy again.
+type CircuitBreaker struct {
+	base  http.RoundTripper
+	opts  BreakerOpts
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+type circuit struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+	passed   int
+	// generation changes with the state, the outcome of a request admitted
+	// under another one is ignored
+	generation uint64
+}
+
+func (c *circuit) set(state BreakerState) {
+	c.state, c.failures, c.probes, c.passed = state, 0, 0, 0
+	c.generation++
+	if state == BreakerOpen {
+		c.openedAt = time.Now()
+	}
+}
+
+// NewCircuitBreaker returns a circuit breaker in front of base.
+func NewCircuitBreaker(base http.RoundTripper, opts BreakerOpts) *CircuitBreaker {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &CircuitBreaker{base: base, opts: opts.withDefaults(), hosts: make(map[string]*circuit)}
+}
+
+// State returns the state of the circuit of host.
+func (b *CircuitBreaker) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.hosts[host]
+	if !ok {
+		return BreakerClosed
+	}
+	if c.state == BreakerOpen && time.Since(c.openedAt) >= b.opts.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return c.state
+}
+
+func (b *CircuitBreaker) RoundTrip(r *http.Request) (*http.Response, error) {
+	host := r.URL.Host
+	generation, err := b.acquire(host)
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.base.RoundTrip(r)
+	switch {
+	case err != nil && r.Context().Err() != nil:
+		// cancelled by the caller, which says nothing of the host
+		b.release(host, generation)
+	case b.opts.IsFailure(res, err):
+		b.done(host, generation, false)
+	default:
+		b.done(host, generation, true)
+	}
+	return res, err
+}
+
+// acquire lets a request through the circuit of host, returning the
+// generation it is admitted under, or fails it fast.
+func (b *CircuitBreaker) acquire(host string) (uint64, error) {
+	b.mu.Lock()
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+	from := c.state
+	if c.state == BreakerOpen && time.Since(c.openedAt) >= b.opts.OpenTimeout {
+		c.set(BreakerHalfOpen)
+	}
+	var err error
+	switch c.state {
+	case BreakerOpen:
+		err = ErrCircuitOpen
+	case BreakerHalfOpen:
+		if c.probes >= b.opts.HalfOpenRequests {
+			err = ErrCircuitOpen
+		} else {
+			c.probes++
+		}
+	}
+	to, generation := c.state, c.generation
+	b.mu.Unlock()
+	b.changed(host, from, to)
+	return generation, err
+}
+
+// release gives back the probe of a request which did not complete.
+func (b *CircuitBreaker) release(host string, generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.hosts[host]; c.generation == generation && c.state == BreakerHalfOpen && c.probes > c.passed {
+		c.probes--
+	}
+}
+
+// done records the outcome of a request to host admitted under generation.
+func (b *CircuitBreaker) done(host string, generation uint64, success bool) {
+	b.mu.Lock()
+	c := b.hosts[host]
+	from := c.state
+	switch {
+	case c.generation != generation:
+		// admitted before the last change of state, it tells nothing of it
+	case success && c.state == BreakerHalfOpen:
+		c.passed++
+		if c.passed >= b.opts.HalfOpenRequests {
+			c.set(BreakerClosed)
+		}
+	case success:
+		c.failures = 0
+	case c.state == BreakerHalfOpen:
+		c.set(BreakerOpen)
+	case c.state == BreakerClosed:
+		c.failures++
+		if c.failures >= b.opts.FailureThreshold {
+			c.set(BreakerOpen)
+		}
+	}
+	to := c.state
+	b.mu.Unlock()
+	b.changed(host, from, to)
+}
+
+func (b *CircuitBreaker) changed(host string, from, to BreakerState) {
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(host, from, to)
+	}
+}
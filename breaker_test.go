@@ -0,0 +1,222 @@
+package bifrost
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		hits    int32
+		healthy int32
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer other.Close()
+	host := upstream.Listener.Addr().String()
+
+	var (
+		mu          sync.Mutex
+		transitions []string
+	)
+	breaker := NewCircuitBreaker(nil, BreakerOpts{
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		OnStateChange: func(h string, from, to BreakerState) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, host, h)
+			transitions = append(transitions, from.String()+">"+to.String())
+		},
+	})
+	client := &http.Client{Transport: breaker}
+	get := func(target string) (int, error) {
+		res, err := client.Get(target)
+		if err != nil {
+			return 0, err
+		}
+		res.Body.Close()
+		return res.StatusCode, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		status, err := get(upstream.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, status)
+	}
+	assert.Equal(t, BreakerOpen, breaker.State(host))
+	_, err := get(upstream.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+	status, err := get(other.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, BreakerClosed, breaker.State(other.Listener.Addr().String()))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, BreakerHalfOpen, breaker.State(host))
+	status, err = get(upstream.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, BreakerOpen, breaker.State(host))
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&healthy, 1)
+	status, err = get(upstream.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, BreakerClosed, breaker.State(host))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{
+		"closed>open",
+		"open>half-open",
+		"half-open>open",
+		"open>half-open",
+		"half-open>closed",
+	}, transitions)
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	release := make(chan struct{})
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		<-release
+	}))
+	defer upstream.Close()
+	breaker := NewCircuitBreaker(nil, BreakerOpts{FailureThreshold: 1, OpenTimeout: time.Millisecond, HalfOpenRequests: 2})
+	client := &http.Client{Transport: breaker}
+
+	res, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	time.Sleep(2 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(upstream.URL)
+			if assert.NoError(t, err) {
+				res.Body.Close()
+			}
+		}()
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&hits) == 3 }, time.Second, time.Millisecond)
+	_, err = client.Get(upstream.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	close(release)
+	wg.Wait()
+
+	u, _ := url.Parse(upstream.URL)
+	assert.Equal(t, BreakerClosed, breaker.State(u.Host))
+}
+
+func TestClientRetryBreaker(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	var opened int32
+	client, err := NewClient(ClientOpts{
+		Retry: &RetryOpts{MaxAttempts: 5, BaseDelay: time.Millisecond},
+		Breaker: &BreakerOpts{FailureThreshold: 2, OnStateChange: func(host string, from, to BreakerState) {
+			if to == BreakerOpen {
+				atomic.AddInt32(&opened, 1)
+			}
+		}},
+	})
+	assert.NoError(t, err)
+	_, err = client.Get(upstream.URL)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), hits)
+	assert.Equal(t, int32(1), opened)
+}
+
+func TestCircuitBreakerStaleOutcomes(t *testing.T) {
+	release := map[string]chan struct{}{
+		"/slow-ok":    make(chan struct{}),
+		"/slow-fail":  make(chan struct{}),
+		"/slow-probe": make(chan struct{}),
+	}
+	var inFlight int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, ok := release[r.URL.Path]; ok {
+			atomic.AddInt32(&inFlight, 1)
+			<-c
+		}
+		if r.URL.Path == "/fail" || r.URL.Path == "/slow-fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+	u, _ := url.Parse(upstream.URL)
+	breaker := NewCircuitBreaker(nil, BreakerOpts{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond})
+	client := &http.Client{Transport: breaker}
+	get := func(path string) (int, error) {
+		res, err := client.Get(upstream.URL + path)
+		if err != nil {
+			return 0, err
+		}
+		res.Body.Close()
+		return res.StatusCode, nil
+	}
+	slow := func(path string) chan int {
+		status := make(chan int, 1)
+		go func() {
+			code, err := get(path)
+			assert.NoError(t, err)
+			status <- code
+		}()
+		return status
+	}
+
+	// admitted while closed, finishing once the breaker is half-open
+	slowOK := slow("/slow-ok")
+	slowFail := slow("/slow-fail")
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&inFlight) == 2 }, time.Second, time.Millisecond)
+	status, err := get("/fail")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, BreakerOpen, breaker.State(u.Host))
+
+	time.Sleep(30 * time.Millisecond)
+	probe := slow("/slow-probe")
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&inFlight) == 3 }, time.Second, time.Millisecond)
+	_, err = get("/")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	close(release["/slow-ok"])
+	assert.Equal(t, http.StatusOK, <-slowOK)
+	assert.Equal(t, BreakerHalfOpen, breaker.State(u.Host))
+	close(release["/slow-fail"])
+	assert.Equal(t, http.StatusInternalServerError, <-slowFail)
+	assert.Equal(t, BreakerHalfOpen, breaker.State(u.Host))
+
+	close(release["/slow-probe"])
+	assert.Equal(t, http.StatusOK, <-probe)
+	assert.Equal(t, BreakerClosed, breaker.State(u.Host))
+}
@@ -2,34 +2,202 @@ package bifrost
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"time"
 )
 
-// Transport constructs a HTTP client with keep-alive turned
-// off and a dial-timeout of 30 seconds.
-func Transport(tlsInsecure bool, timeout int) http.Client {
-	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   time.Duration(timeout) * time.Second,
-			KeepAlive: 0,
-		}).DialContext,
+// ClientOpts configures the client returned by NewClient, the zero value of
+// a field picking its default.
+type ClientOpts struct {
+	// Timeout bounds a whole exchange, body included, none when 0. The
+	// context of a request bounds it too.
+	Timeout time.Duration
+	// DialTimeout bounds the TCP connect, 30 seconds by default.
+	DialTimeout time.Duration
+	// KeepAlive is the interval of the TCP keep-alive probes, 30 seconds by
+	// default.
+	KeepAlive time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake, 10 seconds by default.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for the response headers once
+	// the request is written, none when 0.
+	ResponseHeaderTimeout time.Duration
+	// ExpectContinueTimeout bounds the wait for a 100-continue, 1 second by
+	// default.
+	ExpectContinueTimeout time.Duration
+	// IdleConnTimeout closes the connections idle for longer, 90 seconds by
+	// default.
+	IdleConnTimeout time.Duration
+
+	// MaxIdleConns bounds the idle connections kept, 100 by default.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost bounds the idle connections kept per host, 10 by
+	// default.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost bounds the connections per host, dialing or in use,
+	// none when 0.
+	MaxConnsPerHost int
+	// DisableKeepAlives opens a connection per request.
+	DisableKeepAlives bool
+	// DisableHTTP2 keeps the client on HTTP/1.1.
+	DisableHTTP2 bool
+
+	// CAFile is a PEM bundle of the authorities trusted on top of the
+	// system ones.
+	CAFile string
+	// CAPEM holds PEM certificates of authorities trusted on top of the
+	// system ones.
+	CAPEM []byte
+	// CertFile and KeyFile are the PEM certificate and key the client
+	// authenticates itself with (mTLS).
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify accepts any server certificate, for tests only.
+	InsecureSkipVerify bool
+
+	// Proxy returns the proxy of a request, http.ProxyFromEnvironment when
+	// nil. http.ProxyURL sets a fixed one.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// Retry enables the retries of the failed requests.
+	Retry *RetryOpts
+	// Breaker enables a circuit breaker per host, in front of which each
+	// attempt of a retried request goes.
+	Breaker *BreakerOpts
+	// Telemetry enables a span and a log line per attempt of a request.
+	Telemetry *ClientTelemetryOpts
+	// RequestIDHeader carries the request id of the context of a request,
+	// X-Request-Id when empty.
+	RequestIDHeader string
+}
 
-		MaxIdleConns:          1,
-		DisableKeepAlives:     true,
-		IdleConnTimeout:       time.Duration(timeout*2) * time.Millisecond,
-		ExpectContinueTimeout: time.Duration(timeout*10) * time.Millisecond,
+func (o ClientOpts) withDefaults() ClientOpts {
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = 30 * time.Second
+	}
+	if o.KeepAlive <= 0 {
+		o.KeepAlive = 30 * time.Second
+	}
+	if o.TLSHandshakeTimeout <= 0 {
+		o.TLSHandshakeTimeout = 10 * time.Second
+	}
+	if o.ExpectContinueTimeout <= 0 {
+		o.ExpectContinueTimeout = time.Second
+	}
+	if o.IdleConnTimeout <= 0 {
+		o.IdleConnTimeout = 90 * time.Second
+	}
+	if o.MaxIdleConns <= 0 {
+		o.MaxIdleConns = 100
+	}
+	if o.MaxIdleConnsPerHost <= 0 {
+		o.MaxIdleConnsPerHost = 10
+	}
+	if o.Proxy == nil {
+		o.Proxy = http.ProxyFromEnvironment
 	}
+	if o.RequestIDHeader == "" {
+		o.RequestIDHeader = HeaderXRequestID
+	}
+	return o
+}
+
+func (o ClientOpts) tlsConfig() (*tls.Config, error) {
 	// #nosec
-	if tlsInsecure {
-		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: tlsInsecure}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CAFile != "" || len(o.CAPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if o.CAFile != "" {
+			pem, err := os.ReadFile(o.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("bifrost: read ca file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("bifrost: no certificate in ca file %s", o.CAFile)
+			}
+		}
+		if len(o.CAPEM) > 0 && !pool.AppendCertsFromPEM(o.CAPEM) {
+			return nil, fmt.Errorf("bifrost: no certificate in ca pem")
+		}
+		config.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("bifrost: load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
 	}
+	return config, nil
+}
 
-	proxyClient := http.Client{
-		Transport: tr,
+// NewClient returns a HTTP client pooling its connections, speaking HTTP/2
+// to the servers which offer it. The request id of the context of a request
+// is sent in its RequestIDHeader.
+func NewClient(opts ClientOpts) (*http.Client, error) {
+	opts = opts.withDefaults()
+	config, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
 	}
+	tr := &http.Transport{
+		Proxy: opts.Proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   opts.DialTimeout,
+			KeepAlive: opts.KeepAlive,
+		}).DialContext,
+		TLSClientConfig:       config,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		ExpectContinueTimeout: opts.ExpectContinueTimeout,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		DisableKeepAlives:     opts.DisableKeepAlives,
+		ForceAttemptHTTP2:     !opts.DisableHTTP2,
+	}
+	if opts.DisableHTTP2 {
+		// a non nil empty map turns the HTTP/2 upgrade off
+		tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	var rt http.RoundTripper = tr
+	if opts.Breaker != nil {
+		rt = NewCircuitBreaker(rt, *opts.Breaker)
+	}
+	if opts.Telemetry != nil {
+		telemetry := *opts.Telemetry
+		if telemetry.RequestIDHeader == "" {
+			telemetry.RequestIDHeader = opts.RequestIDHeader
+		}
+		rt = NewTracedTransport(rt, telemetry)
+	}
+	if opts.Retry != nil {
+		rt = NewRetryTransport(rt, *opts.Retry)
+	}
+	return &http.Client{
+		Transport: requestIDTransport{base: rt, header: opts.RequestIDHeader},
+		Timeout:   opts.Timeout,
+	}, nil
+}
 
-	return proxyClient
+// Transport constructs a HTTP client dialing with a timeout of timeout
+// seconds, the other options of NewClient left to their defaults.
+//
+// Deprecated: use NewClient.
+func Transport(tlsInsecure bool, timeout int) http.Client {
+	// the options read no file, NewClient cannot fail
+	client, _ := NewClient(ClientOpts{
+		DialTimeout:        time.Duration(timeout) * time.Second,
+		InsecureSkipVerify: tlsInsecure,
+	})
+	return *client
 }
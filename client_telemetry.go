@@ -0,0 +1,129 @@
+package bifrost
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ClientTelemetryOpts configures the round-tripper returned by
+// NewTracedTransport.
+type ClientTelemetryOpts struct {
+	// Propagations are the formats the trace context is sent in, the global
+	// propagator of otel when empty.
+	Propagations []Propagation
+	// TracerProvider starts the spans, the global provider of otel when nil.
+	TracerProvider trace.TracerProvider
+	// SpanName names the span of a request, "HTTP METHOD" by default.
+	SpanName func(r *http.Request) string
+	// Level is the level of the line logged per request, debug being the
+	// zero value. zerolog.Disabled logs none.
+	Level zerolog.Level
+	// RequestIDHeader carries the request id of the context of a request,
+	// X-Request-Id when empty.
+	RequestIDHeader string
+}
+
+func (o ClientTelemetryOpts) tracer() trace.Tracer {
+	if o.TracerProvider != nil {
+		return o.TracerProvider.Tracer("http.client")
+	}
+	return otel.Tracer("http.client")
+}
+
+func (o ClientTelemetryOpts) requestIDHeader() string {
+	if o.RequestIDHeader == "" {
+		return HeaderXRequestID
+	}
+	return o.RequestIDHeader
+}
+
+func (o ClientTelemetryOpts) spanName(r *http.Request) string {
+	if o.SpanName != nil {
+		return o.SpanName(r)
+	}
+	return "HTTP " + r.Method
+}
+
+type tracedTransport struct {
+	base       http.RoundTripper
+	opts       ClientTelemetryOpts
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracedTransport returns a round-tripper running the requests of base in
+// client spans, whose trace context it sends along with the request id of
+// the context. Each request is logged with the logger of its context, with
+// its method, url, status and latency.
+func NewTracedTransport(base http.RoundTripper, opts ClientTelemetryOpts) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tracedTransport{
+		base:       base,
+		opts:       opts,
+		propagator: TracerOpts{Propagations: opts.Propagations}.propagator(),
+	}
+}
+
+func (t *tracedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	ctx, span := t.opts.tracer().Start(r.Context(), t.opts.spanName(r),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(clientAttributes(r)...))
+	defer span.End()
+
+	req := r.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if id := GetRequestID(ctx); id != "" {
+		span.SetAttributes(attribute.String("request.id", id))
+		if header := t.opts.requestIDHeader(); req.Header.Get(header) == "" {
+			req.Header.Set(header, id)
+		}
+	}
+
+	res, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+	status := 0
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	} else {
+		status = res.StatusCode
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, trace.SpanKindClient))
+	}
+
+	if t.opts.Level != zerolog.Disabled {
+		event := GetLogger(r.Context()).WithLevel(t.opts.Level).
+			Str("method", r.Method).
+			Str("url", r.URL.Redacted()).
+			Int("status", status).
+			Dur("latency", elapsed).
+			Str("span_id", span.SpanContext().SpanID().String())
+		if err != nil {
+			event = event.Err(err)
+		}
+		event.Msg("outbound request")
+	}
+	return res, err
+}
+
+// clientAttributes are the semconv attributes of r, its url without the
+// credentials.
+func clientAttributes(r *http.Request) []attribute.KeyValue {
+	if r.URL.User != nil {
+		u := *r.URL
+		u.User = nil
+		r = r.Clone(r.Context())
+		r.URL = &u
+	}
+	return semconv.HTTPClientAttributesFromHTTPRequest(r)
+}
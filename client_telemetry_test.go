@@ -0,0 +1,135 @@
+package bifrost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracedTransport(t *testing.T) {
+	received := make(chan http.Header, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+
+	spans := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	client, err := NewClient(ClientOpts{Telemetry: &ClientTelemetryOpts{
+		Propagations:   []Propagation{PropagationTraceContext},
+		TracerProvider: provider,
+		Level:          zerolog.InfoLevel,
+	}})
+	assert.NoError(t, err)
+
+	var logged bytes.Buffer
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	ctx = WithRequestID(WithLogger(ctx, zerolog.New(&logged)), "req-1")
+	get := func(target string) *http.Response {
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		assert.NoError(t, err)
+		res, err := client.Do(r)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	target := strings.Replace(upstream.URL, "http://", "http://orders:secret@", 1) + "/orders/42"
+	res := get(target)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	header := <-received
+	assert.Equal(t, "req-1", header.Get(HeaderXRequestID))
+
+	ended := spans.GetSpans()
+	assert.Len(t, ended, 1)
+	span := ended[0]
+	assert.Equal(t, "HTTP GET", span.Name)
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+	assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+	assert.Equal(t, "00-"+span.SpanContext.TraceID().String()+"-"+span.SpanContext.SpanID().String()+"-01", header.Get("traceparent"))
+	assert.Contains(t, span.Attributes, semconv.HTTPMethodKey.String(http.MethodGet))
+	assert.Contains(t, span.Attributes, semconv.HTTPURLKey.String(upstream.URL+"/orders/42"))
+	assert.Contains(t, span.Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusOK))
+	assert.Equal(t, otelcodes.Unset, span.Status.Code)
+
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logged.Bytes(), &line))
+	assert.Equal(t, "info", line["level"])
+	assert.Equal(t, "outbound request", line["message"])
+	assert.Equal(t, http.MethodGet, line["method"])
+	assert.Equal(t, strings.Replace(target, "secret", "xxxxx", 1), line["url"])
+	assert.Equal(t, float64(http.StatusOK), line["status"])
+	assert.Equal(t, span.SpanContext.SpanID().String(), line["span_id"])
+	assert.Contains(t, line, "latency")
+
+	res = get(upstream.URL + "/fail")
+	<-received
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	span = spans.GetSpans()[1]
+	assert.Equal(t, otelcodes.Error, span.Status.Code)
+	assert.Contains(t, span.Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusInternalServerError))
+}
+
+func TestTracedTransportErrors(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target := upstream.URL
+	upstream.Close()
+
+	spans := tracetest.NewInMemoryExporter()
+	rt := NewTracedTransport(nil, ClientTelemetryOpts{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))})
+	var logged bytes.Buffer
+	r, err := http.NewRequestWithContext(WithLogger(context.Background(), zerolog.New(&logged)), http.MethodPost, target, nil)
+	assert.NoError(t, err)
+	_, err = rt.RoundTrip(r)
+	assert.Error(t, err)
+
+	span := spans.GetSpans()[0]
+	assert.Equal(t, "HTTP POST", span.Name)
+	assert.Equal(t, otelcodes.Error, span.Status.Code)
+	assert.Len(t, span.Events, 1)
+	assert.Contains(t, logged.String(), `"level":"debug"`)
+	assert.Contains(t, logged.String(), `"status":0`)
+	assert.Contains(t, logged.String(), `"error":"`)
+
+	logged.Reset()
+	rt = NewTracedTransport(nil, ClientTelemetryOpts{TracerProvider: sdktrace.NewTracerProvider(), Level: zerolog.Disabled})
+	_, err = rt.RoundTrip(r)
+	assert.Error(t, err)
+	assert.Empty(t, logged.String())
+}
+
+func TestTracedTransportRetries(t *testing.T) {
+	var hits int32
+	upstream := flakyServer(t, &hits, nil, http.StatusServiceUnavailable)
+	spans := tracetest.NewInMemoryExporter()
+	client, err := NewClient(ClientOpts{
+		Retry:     &RetryOpts{BaseDelay: time.Millisecond},
+		Telemetry: &ClientTelemetryOpts{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)), Level: zerolog.Disabled},
+	})
+	assert.NoError(t, err)
+	res, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+
+	ended := spans.GetSpans()
+	assert.Len(t, ended, 2)
+	assert.Contains(t, ended[0].Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusServiceUnavailable))
+	assert.Contains(t, ended[1].Attributes, semconv.HTTPStatusCodeKey.Int(http.StatusOK))
+}
@@ -0,0 +1,132 @@
+package bifrost
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clientTransport(t *testing.T, client *http.Client) *http.Transport {
+	tr, ok := client.Transport.(requestIDTransport)
+	assert.True(t, ok)
+	return tr.base.(*http.Transport)
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	client, err := NewClient(ClientOpts{Timeout: 5 * time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, client.Timeout)
+
+	tr := clientTransport(t, client)
+	assert.False(t, tr.DisableKeepAlives)
+	assert.Equal(t, 100, tr.MaxIdleConns)
+	assert.Equal(t, 10, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 90*time.Second, tr.IdleConnTimeout)
+	assert.Equal(t, 10*time.Second, tr.TLSHandshakeTimeout)
+	assert.Equal(t, time.Second, tr.ExpectContinueTimeout)
+	assert.True(t, tr.ForceAttemptHTTP2)
+	assert.Equal(t, uint16(tls.VersionTLS12), tr.TLSClientConfig.MinVersion)
+	assert.Nil(t, tr.TLSClientConfig.RootCAs)
+}
+
+func TestNewClientMTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir(), x509.ExtKeyUsageClientAuth)
+	clientPEM, err := os.ReadFile(certFile)
+	assert.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	assert.True(t, clientCAs.AppendCertsFromPEM(clientPEM))
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto+" "+r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	upstream.EnableHTTP2 = true
+	upstream.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	upstream.StartTLS()
+	defer upstream.Close()
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw})
+
+	get := func(client *http.Client) (string, error) {
+		res, err := client.Get(upstream.URL)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return string(body), err
+	}
+
+	client, err := NewClient(ClientOpts{CAPEM: serverCA, CertFile: certFile, KeyFile: keyFile})
+	assert.NoError(t, err)
+	body, err := get(client)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0 localhost", body)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, serverCA, 0o600))
+	client, err = NewClient(ClientOpts{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, DisableHTTP2: true})
+	assert.NoError(t, err)
+	body, err = get(client)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 localhost", body)
+
+	client, err = NewClient(ClientOpts{CAPEM: serverCA})
+	assert.NoError(t, err)
+	_, err = get(client)
+	assert.Error(t, err)
+
+	client, err = NewClient(ClientOpts{CertFile: certFile, KeyFile: keyFile})
+	assert.NoError(t, err)
+	_, err = get(client)
+	assert.Error(t, err)
+}
+
+func TestNewClientInvalid(t *testing.T) {
+	_, err := NewClient(ClientOpts{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+	_, err = NewClient(ClientOpts{CAPEM: []byte("not a certificate")})
+	assert.EqualError(t, err, "bifrost: no certificate in ca pem")
+	_, err = NewClient(ClientOpts{CertFile: "client.pem"})
+	assert.Error(t, err)
+}
+
+func TestNewClientProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	assert.NoError(t, err)
+
+	client, err := NewClient(ClientOpts{Proxy: http.ProxyURL(proxyURL)})
+	assert.NoError(t, err)
+	res, err := client.Get("http://orders.internal/orders/42")
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, "http://orders.internal/orders/42", requested)
+}
+
+func TestTransport(t *testing.T) {
+	client := Transport(true, 5)
+	tr := clientTransport(t, &client)
+	assert.False(t, tr.DisableKeepAlives)
+	assert.Equal(t, 10, tr.MaxIdleConnsPerHost)
+	assert.Equal(t, 90*time.Second, tr.IdleConnTimeout)
+	assert.True(t, tr.TLSClientConfig.InsecureSkipVerify)
+
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+	res, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+}
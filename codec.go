@@ -0,0 +1,263 @@
+package bifrost
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Codec encodes response payloads and decodes request bodies of a media type.
+type Codec interface {
+	// ContentType is the Content-Type header of the encoded payloads.
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{m: make(map[string]Codec)}
+
+func init() {
+	RegisterCodec(MIMEApplicationJSON, JSONCodec{})
+	RegisterCodec(MIMEApplicationXML, XMLCodec{})
+	RegisterCodec(MIMETextXML, XMLCodec{})
+	RegisterCodec(MIMEApplicationMsgpack, MsgpackCodec{})
+	RegisterCodec(MIMEApplicationXMsgpack, MsgpackCodec{})
+	RegisterCodec(MIMEApplicationProtobuf, ProtobufCodec{})
+	RegisterCodec(MIMEApplicationXProtobuf, ProtobufCodec{})
+}
+
+// RegisterCodec makes c the codec of the media type, replacing the one
+// registered before.
+func RegisterCodec(mediaType string, c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[strings.ToLower(mediaType)] = c
+}
+
+// GetCodec returns the codec of a media type, parameters such as the charset
+// are ignored.
+func GetCodec(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.m[mediaType]
+	return c, ok
+}
+
+// JSONCodec encodes as ResponseJSONPayload always has, html escaped with a
+// trailing newline.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return MIMEApplicationJSONCharsetUTF8
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// XMLCodec encodes with encoding/xml. The Response envelope is written as a
+// <response> element, maps as one element per key and slices as <item>
+// elements. A key which is not a valid XML name, such as "2fa" or
+// "full name", fails the encoding rather than producing invalid XML.
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string {
+	return MIMEApplicationXMLCharsetUTF8
+}
+
+func (XMLCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+func (XMLCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// MarshalXML writes the envelope with the element names of its json keys.
+func (r Response) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "response"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"version", r.Version},
+		{"meta", r.Meta},
+		{"data", r.Data},
+		{"pagination", r.Pagination},
+	} {
+		if f.value == nil {
+			continue
+		}
+		if err := encodeXMLValue(e, f.name, reflect.ValueOf(f.value)); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func encodeXMLValue(e *xml.Encoder, name string, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !validXMLName(name) {
+		return fmt.Errorf("xml: %q is not a valid element name", name)
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch v.Kind() {
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := encodeXMLValue(e, fmt.Sprint(key.Interface()), v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.EncodeElement(v.Interface(), start)
+		}
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		for n := 0; n < v.Len(); n++ {
+			if err := encodeXMLValue(e, "item", v.Index(n)); err != nil {
+				return err
+			}
+		}
+		return e.EncodeToken(start.End())
+	case reflect.Float32, reflect.Float64:
+		return e.EncodeElement(strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), start)
+	default:
+		return e.EncodeElement(v.Interface(), start)
+	}
+}
+
+// validXMLName accepts the names of the XML specification, colons aside
+// since no namespace is declared.
+func validXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for n, c := range name {
+		switch {
+		case c == '_' || unicode.IsLetter(c):
+		case n > 0 && (c == '-' || c == '.' || unicode.IsDigit(c)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// MsgpackCodec encodes with MessagePack, structs keyed by their json tags.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string {
+	return MIMEApplicationMsgpack
+}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := msgpack.NewEncoder(buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// ProtobufCodec encodes proto messages as they are. Any other value, the
+// Response envelope included, is encoded as a google.protobuf.Struct holding
+// its json representation. The numbers of a Struct are doubles: integers
+// beyond 2^53, such as int64 ids, lose precision, so send them as strings or
+// as a proto message.
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string {
+	return MIMEApplicationProtobuf
+}
+
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("protobuf: %T is neither a proto message nor a json object", v)
+	}
+	s, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(s)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+	s := &structpb.Struct{}
+	if err := proto.Unmarshal(data, s); err != nil {
+		return err
+	}
+	b, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
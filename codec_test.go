@@ -0,0 +1,161 @@
+package bifrost
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type shipment struct {
+	XMLName xml.Name `json:"-" xml:"shipment" msgpack:"-"`
+	Courier string   `json:"courier" xml:"courier"`
+	Weight  float64  `json:"weight" xml:"weight"`
+}
+
+func encodeEnvelope(t *testing.T, mediaType string) *httptest.ResponseRecorder {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	err = ResponseCodecPayload(w, r, http.StatusCreated, mediaType,
+		map[string]interface{}{"message": "created"},
+		[]shipment{{Courier: "jne", Weight: 1.5}},
+		Pagination{Limit: 10},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	return w
+}
+
+func TestResponseXML(t *testing.T) {
+	w := encodeEnvelope(t, MIMEApplicationXML)
+	assert.Equal(t, MIMEApplicationXMLCharsetUTF8, w.Header().Get(HeaderContentType))
+	assert.Equal(t, xml.Header+
+		`<response>`+
+		`<version><label>v1</label><number>0.1.0</number></version>`+
+		`<meta><code>Created</code></meta>`+
+		`<data><message>created</message><shipment><item><courier>jne</courier><weight>1.5</weight></item></shipment></data>`+
+		`<pagination><limit>10</limit></pagination>`+
+		`</response>`, w.Body.String())
+}
+
+func TestResponseMsgpack(t *testing.T) {
+	w := encodeEnvelope(t, MIMEApplicationMsgpack)
+	assert.Equal(t, MIMEApplicationMsgpack, w.Header().Get(HeaderContentType))
+
+	var actual map[string]interface{}
+	assert.NoError(t, MsgpackCodec{}.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, map[string]interface{}{"label": "v1", "number": "0.1.0"}, actual["version"])
+	assert.Equal(t, map[string]interface{}{"code": "Created"}, actual["meta"])
+	assert.Equal(t, map[string]interface{}{"limit": int8(10), "next_cursor": nil}, actual["pagination"])
+	data := actual["data"].(map[string]interface{})
+	assert.Equal(t, "created", data["message"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"courier": "jne", "weight": 1.5}}, data["shipment"])
+}
+
+func TestResponseProtobuf(t *testing.T) {
+	w := encodeEnvelope(t, MIMEApplicationProtobuf)
+	assert.Equal(t, MIMEApplicationProtobuf, w.Header().Get(HeaderContentType))
+
+	s := &structpb.Struct{}
+	assert.NoError(t, proto.Unmarshal(w.Body.Bytes(), s))
+	assert.Equal(t, map[string]interface{}{
+		"version":    map[string]interface{}{"label": "v1", "number": "0.1.0"},
+		"meta":       map[string]interface{}{"code": "Created"},
+		"data":       map[string]interface{}{"message": "created", "shipment": []interface{}{map[string]interface{}{"courier": "jne", "weight": 1.5}}},
+		"pagination": map[string]interface{}{"limit": float64(10), "next_cursor": nil},
+	}, s.AsMap())
+}
+
+func bindWith(t *testing.T, mediaType string, body []byte, i interface{}) error {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	assert.NoError(t, err)
+	r.Header.Set(HeaderContentType, mediaType)
+	return BindBody(r, i)
+}
+
+func TestBindBodyCodecs(t *testing.T) {
+	expected := shipment{Courier: "jne", Weight: 1.5}
+
+	var fromXML shipment
+	assert.NoError(t, bindWith(t, MIMEApplicationXMLCharsetUTF8, []byte(`<shipment><courier>jne</courier><weight>1.5</weight></shipment>`), &fromXML))
+	assert.Equal(t, expected.Courier, fromXML.Courier)
+	assert.Equal(t, expected.Weight, fromXML.Weight)
+
+	b, err := MsgpackCodec{}.Marshal(expected)
+	assert.NoError(t, err)
+	var fromMsgpack shipment
+	assert.NoError(t, bindWith(t, MIMEApplicationXMsgpack, b, &fromMsgpack))
+	assert.Equal(t, expected, fromMsgpack)
+
+	b, err = proto.Marshal(wrapperspb.String("jne"))
+	assert.NoError(t, err)
+	fromProto := &wrapperspb.StringValue{}
+	assert.NoError(t, bindWith(t, MIMEApplicationProtobuf, b, fromProto))
+	assert.Equal(t, "jne", fromProto.GetValue())
+}
+
+type upperCodec struct{}
+
+func (upperCodec) ContentType() string {
+	return "text/x-upper"
+}
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := JSONCodec{}.Marshal(v)
+	return bytes.ToUpper(b), err
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	return JSONCodec{}.Unmarshal(bytes.ToLower(data), v)
+}
+
+func TestRegisterCodec(t *testing.T) {
+	RegisterCodec("text/x-upper", upperCodec{})
+
+	var s shipment
+	assert.NoError(t, bindWith(t, "text/x-upper", []byte(`{"COURIER":"JNE","WEIGHT":2}`), &s))
+	assert.Equal(t, "jne", s.Courier)
+
+	w := encodeEnvelope(t, "text/x-upper")
+	assert.Equal(t, "text/x-upper", w.Header().Get(HeaderContentType))
+	assert.True(t, strings.HasPrefix(w.Body.String(), `{"VERSION":`))
+}
+
+func TestResponseXMLInvalidName(t *testing.T) {
+	for _, key := range []string{"2fa", "full name", "a:b", ""} {
+		r, err := http.NewRequest(http.MethodGet, "/", nil)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		err = ResponseCodecPayload(w, r, http.StatusOK, MIMEApplicationXML, map[string]interface{}{key: true})
+		assert.EqualError(t, err, fmt.Sprintf("xml: %q is not a valid element name", key))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Body.String())
+	}
+	assert.True(t, validXMLName("_id"))
+	assert.True(t, validXMLName("unit-price.v2"))
+	assert.True(t, validXMLName("café"))
+}
+
+func TestResponseProtobufLargeIntegers(t *testing.T) {
+	b, err := ProtobufCodec{}.Marshal(map[string]interface{}{"id": int64(9007199254740993), "small": int64(9007199254740992)})
+	assert.NoError(t, err)
+	var actual struct {
+		ID    float64 `json:"id"`
+		Small int64   `json:"small"`
+	}
+	assert.NoError(t, ProtobufCodec{}.Unmarshal(b, &actual))
+	// documented: a Struct holds doubles, integers beyond 2^53 are rounded
+	assert.Equal(t, float64(9007199254740992), actual.ID)
+	assert.Equal(t, int64(9007199254740992), actual.Small)
+}
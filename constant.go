@@ -7,10 +7,19 @@ const (
 	HeaderContentLength            = "Content-Length"
 	HeaderContentType              = "Content-Type"
 	HeaderCookie                   = "Cookie"
+	HeaderAccept                   = "Accept"
 	HeaderXCSRFToken               = "X-CSRF-Token"
 	HeaderAccessControlAllowOrigin = "Access-Control-Allow-Origin"
 	HeaderXTraceId                 = "X-Trace-Id"
 	HeaderUberTraceId              = "Uber-Trace-Id"
+	HeaderXStreamError             = "X-Stream-Error"
+	HeaderLink                     = "Link"
+	HeaderDeprecation              = "Deprecation"
+	HeaderSunset                   = "Sunset"
+	HeaderXRequestID               = "X-Request-Id"
+	HeaderRetryAfter               = "Retry-After"
+	HeaderIdempotencyKey           = "Idempotency-Key"
+	HeaderXIdempotencyKey          = "X-Idempotency-Key"
 )
 
 // MIME types
@@ -18,6 +27,7 @@ const (
 	charsetUTF8                          = "charset=UTF-8"
 	MIMEApplicationJSON                  = "application/json"
 	MIMEApplicationJSONCharsetUTF8       = MIMEApplicationJSON + "; " + charsetUTF8
+	MIMEApplicationProblemJSON           = "application/problem+json"
 	MIMEApplicationJavaScript            = "application/javascript"
 	MIMEApplicationJavaScriptCharsetUTF8 = MIMEApplicationJavaScript + "; " + charsetUTF8
 	MIMEApplicationXML                   = "application/xml"
@@ -26,7 +36,10 @@ const (
 	MIMETextXMLCharsetUTF8               = MIMETextXML + "; " + charsetUTF8
 	MIMEApplicationForm                  = "application/x-www-form-urlencoded"
 	MIMEApplicationProtobuf              = "application/protobuf"
+	MIMEApplicationXProtobuf             = "application/x-protobuf"
 	MIMEApplicationMsgpack               = "application/msgpack"
+	MIMEApplicationXMsgpack              = "application/x-msgpack"
+	MIMEApplicationGRPC                  = "application/grpc"
 	MIMETextCSV                          = "text/csv"
 	MIMETextCSVCharsetUTF8               = MIMETextCSV + "; " + charsetUTF8
 	MIMETextHTML                         = "text/html"
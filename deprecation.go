@@ -0,0 +1,143 @@
+package bifrost
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var DeprecationCtxKey = &ctxRender{"deprecation"}
+
+// DeprecationOpts describes the deprecation of a route or an API version.
+type DeprecationOpts struct {
+	// Since is when the route was deprecated, the Deprecation header is
+	// "true" when it is unknown.
+	Since time.Time
+	// Sunset is when the route stops answering, omitted when zero.
+	Sunset time.Time
+	// Successor is the URL of the version replacing the route.
+	Successor string
+	// Message is the warning added to the response Meta, a default one
+	// mentioning the sunset when empty.
+	Message string
+	// Client identifies the caller whose calls are counted, the remote IP
+	// address by default.
+	Client func(r *http.Request) string
+	// MaxClients bounds the clients counted apart, 1000 by default. The
+	// calls of the others are counted under "other".
+	MaxClients int
+}
+
+// otherClients counts the calls of the clients beyond MaxClients.
+const otherClients = "other"
+
+// Deprecation marks the routes it wraps as deprecated and counts their calls
+// per client, up to MaxClients, logging the first call of each client.
+//
+//	deprecation := bifrost.NewDeprecation(bifrost.DeprecationOpts{
+//		Since:     time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC),
+//		Sunset:    time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+//		Successor: "/v2/orders",
+//	})
+//	versions.Handle(bifrost.Version{Label: "v1"}, deprecation.Handler(v1Router))
+type Deprecation struct {
+	opts    DeprecationOpts
+	mu      sync.Mutex
+	clients map[string]int64
+}
+
+func NewDeprecation(opts DeprecationOpts) *Deprecation {
+	if opts.Client == nil {
+		opts.Client = remoteIP
+	}
+	if opts.MaxClients < 1 {
+		opts.MaxClients = 1000
+	}
+	if opts.Message == "" {
+		opts.Message = "this endpoint is deprecated"
+		if !opts.Sunset.IsZero() {
+			opts.Message += fmt.Sprintf(" and will be removed on %s", opts.Sunset.UTC().Format(time.RFC3339))
+		}
+		if opts.Successor != "" {
+			opts.Message += fmt.Sprintf(", use %s instead", opts.Successor)
+		}
+	}
+	return &Deprecation{opts: opts, clients: make(map[string]int64)}
+}
+
+// Handler is a middleware adding the Deprecation (RFC 9745), Sunset
+// (RFC 8594) and successor-version Link headers to the responses, along with
+// a warning in their Meta.
+func (d *Deprecation) Handler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if d.opts.Since.IsZero() {
+			h.Set(HeaderDeprecation, "true")
+		} else {
+			h.Set(HeaderDeprecation, "@"+strconv.FormatInt(d.opts.Since.Unix(), 10))
+		}
+		if !d.opts.Sunset.IsZero() {
+			h.Set(HeaderSunset, d.opts.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.opts.Successor != "" {
+			h.Add(HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, d.opts.Successor))
+		}
+		d.count(r)
+		r = r.WithContext(context.WithValue(r.Context(), DeprecationCtxKey, d.opts.Message))
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+func (d *Deprecation) count(r *http.Request) {
+	client := d.opts.Client(r)
+	d.mu.Lock()
+	if _, ok := d.clients[client]; !ok && len(d.clients) >= d.opts.MaxClients {
+		client = otherClients
+	}
+	d.clients[client]++
+	first := d.clients[client] == 1
+	d.mu.Unlock()
+	if !first {
+		return
+	}
+	event := GetLogger(r.Context()).Warn().
+		Str("client", client).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Time("sunset", d.opts.Sunset)
+	if ver, ok := r.Context().Value(CtxVersion).(Version); ok {
+		event = event.Str("version", ver.Label)
+	}
+	event.Msg("Deprecated endpoint called")
+}
+
+// Calls returns the number of calls of each client so far, those of the
+// clients beyond MaxClients under "other".
+func (d *Deprecation) Calls() map[string]int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	calls := make(map[string]int64, len(d.clients))
+	for client, n := range d.clients {
+		calls[client] = n
+	}
+	return calls
+}
+
+// deprecationWarning is the warning of the Meta answered to r.
+func deprecationWarning(r *http.Request) string {
+	msg, _ := r.Context().Value(DeprecationCtxKey).(string)
+	return msg
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
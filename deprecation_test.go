@@ -0,0 +1,109 @@
+package bifrost
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecation(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	deprecation := NewDeprecation(DeprecationOpts{
+		Since:     time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "/api/v2/orders",
+	})
+	versions := NewVersions(VersioningOpts{})
+	versions.Handle(Version{Label: "v1", Number: "1.4.0"}, deprecation.Handler(HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		if r.URL.Query().Get("fail") != "" {
+			return errOrderNotFound
+		}
+		return ResponseJSONPayload(w, r, http.StatusOK, map[string]interface{}{"id": 1})
+	})))
+	versions.Handle(Version{Label: "v2", Number: "2.0.1"}, HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		return ResponseJSONPayload(w, r, http.StatusOK, map[string]interface{}{"id": 1})
+	}))
+
+	call := func(path string, client string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = client + ":41234"
+		w := httptest.NewRecorder()
+		versions.ServeHTTP(w, r.WithContext(WithLogger(r.Context(), logger)))
+		return w
+	}
+
+	w := call("/v1/orders", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "@1635724800", w.Header().Get(HeaderDeprecation))
+	assert.Equal(t, "Sun, 01 May 2022 00:00:00 GMT", w.Header().Get(HeaderSunset))
+	assert.Equal(t, `</api/v2/orders>; rel="successor-version"`, w.Header().Get(HeaderLink))
+	var actual struct {
+		Meta Meta `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, "this endpoint is deprecated and will be removed on 2022-05-01T00:00:00Z, use /api/v2/orders instead", actual.Meta.Warning)
+
+	w = call("/v1/orders?fail=1", "10.0.0.1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.NotEmpty(t, actual.Meta.Warning)
+
+	call("/v1/orders", "10.0.0.2")
+	w = call("/v2/orders", "10.0.0.3")
+	assert.Empty(t, w.Header().Get(HeaderDeprecation))
+	assert.NotContains(t, w.Body.String(), "warning")
+
+	assert.Equal(t, map[string]int64{"10.0.0.1": 2, "10.0.0.2": 1}, deprecation.Calls())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"client":"10.0.0.1"`)
+	assert.Contains(t, lines[0], `"version":"v1"`)
+	assert.Contains(t, lines[1], `"client":"10.0.0.2"`)
+}
+
+func TestDeprecationClient(t *testing.T) {
+	deprecation := NewDeprecation(DeprecationOpts{
+		Message: "use the v2 orders",
+		Client: func(r *http.Request) string {
+			return r.Header.Get("X-Client-Id")
+		},
+	})
+	handler := deprecation.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "use the v2 orders", deprecationWarning(r))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	for _, client := range []string{"mobile", "mobile", "web"} {
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		r.Header.Set("X-Client-Id", client)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, "true", w.Header().Get(HeaderDeprecation))
+		assert.Empty(t, w.Header().Get(HeaderSunset))
+	}
+	assert.Equal(t, map[string]int64{"mobile": 2, "web": 1}, deprecation.Calls())
+}
+
+func TestDeprecationMaxClients(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	deprecation := NewDeprecation(DeprecationOpts{MaxClients: 2})
+	handler := deprecation.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.1", "10.0.0.3"} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/orders", nil)
+		r.RemoteAddr = ip + ":4321"
+		handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(WithLogger(r.Context(), logger)))
+	}
+	assert.Equal(t, map[string]int64{"10.0.0.1": 2, "10.0.0.2": 1, "other": 3}, deprecation.Calls())
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[2], `"client":"other"`)
+}
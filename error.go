@@ -2,6 +2,7 @@ package bifrost
 
 import (
 	"context"
+	"errors"
 	"net/http"
 )
 
@@ -15,114 +16,173 @@ func (r *ctxError) String() string {
 
 var CtxError = ctxError{Name: "context error"}
 
+// ErrorDetail describes what is wrong with a single field of the request.
+type ErrorDetail struct {
+	Field   string `json:"field" xml:"field"`
+	Code    string `json:"code,omitempty" xml:"code,omitempty"`
+	Message string `json:"message" xml:"message"`
+}
+
+// Error is an error carrying the http status, a machine-readable code and
+// the details HandlerAdapter renders into the response Meta. Type is the
+// problem type URI used when the error is rendered as problem details.
+type Error struct {
+	Status  int
+	Code    string
+	Type    string
+	Message string
+	Details []ErrorDetail
+	Err     error
+}
+
+// NewError returns an error answered with status, code and message.
+func NewError(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.message()
+	case e.Message == "":
+		return e.Err.Error()
+	default:
+		return e.Message + ": " + e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error with the same status and code,
+// so errors returned by Wrap and WithDetails still match their origin.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return e.Status == t.Status && e.Code == t.Code
+}
+
+// Wrap returns a copy of the error caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithDetails returns a copy of the error with details appended.
+func (e *Error) WithDetails(details ...ErrorDetail) *Error {
+	c := *e
+	c.Details = append(append([]ErrorDetail{}, e.Details...), details...)
+	return &c
+}
+
+// StatusCode returns the http status, 500 when it is not set.
+func (e *Error) StatusCode() int {
+	if e.Status < 100 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// message is what the client sees. The cause of a server error is never
+// exposed unless it has been given as Message.
+func (e *Error) message() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Err != nil && e.StatusCode() < http.StatusInternalServerError:
+		return e.Err.Error()
+	default:
+		return http.StatusText(e.StatusCode())
+	}
+}
+
+// AsError finds the *Error in err's chain, anything else becomes an
+// internal server error wrapping err.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Status: http.StatusInternalServerError, Err: err}
+}
+
+func errStatus(w http.ResponseWriter, r *http.Request, code int, err error) error {
+	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, code))
+	setErrorHeaders(w, r)
+	w.WriteHeader(code)
+	return &Error{Status: code, Err: err}
+}
+
 // ErrBadRequest error http StatusBadRequest
 func ErrBadRequest(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusBadRequest))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusBadRequest)
-	return err
+	return errStatus(w, r, http.StatusBadRequest, err)
 }
 
 // ErrUnauthorized error http StatusUnauthorized
 func ErrUnauthorized(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusUnauthorized))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusUnauthorized)
-	return err
+	return errStatus(w, r, http.StatusUnauthorized, err)
 }
 
 // ErrPaymentRequired error http StatusPaymentRequired
 func ErrPaymentRequired(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusPaymentRequired))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusPaymentRequired)
-	return err
+	return errStatus(w, r, http.StatusPaymentRequired, err)
 }
 
 // ErrForbidden error http StatusForbidden
 func ErrForbidden(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusForbidden))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusForbidden)
-	return err
+	return errStatus(w, r, http.StatusForbidden, err)
 }
 
 // ErrMethodNotAllowed error http StatusMethodNotAllowed
 func ErrMethodNotAllowed(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusMethodNotAllowed))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	return err
+	return errStatus(w, r, http.StatusMethodNotAllowed, err)
 }
 
 // ErrNotAcceptable error http StatusNotAcceptable
 func ErrNotAcceptable(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusNotAcceptable))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusNotAcceptable)
-	return err
+	return errStatus(w, r, http.StatusNotAcceptable, err)
 }
 
 // ErrProxyAuthRequired error http StatusProxyAuthRequired
 func ErrProxyAuthRequired(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusProxyAuthRequired))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusProxyAuthRequired)
-	return err
+	return errStatus(w, r, http.StatusProxyAuthRequired, err)
 }
 
 // ErrRequestTimeout error http StatusRequestTimeout
 func ErrRequestTimeout(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusRequestTimeout))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusRequestTimeout)
-	return err
+	return errStatus(w, r, http.StatusRequestTimeout, err)
 }
 
 // ErrUnsupportedMediaType error http StatusUnsupportedMediaType
 func ErrUnsupportedMediaType(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusUnsupportedMediaType))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusUnsupportedMediaType)
-	return err
+	return errStatus(w, r, http.StatusUnsupportedMediaType, err)
 }
 
 // ErrUnprocessableEntity error http StatusUnprocessableEntity
 func ErrUnprocessableEntity(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusUnprocessableEntity))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusUnprocessableEntity)
-	return err
+	return errStatus(w, r, http.StatusUnprocessableEntity, err)
 }
 
 // ErrInternalServerError error http StatusInternalServerError
 func ErrInternalServerError(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusInternalServerError))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusInternalServerError)
-	return err
+	return errStatus(w, r, http.StatusInternalServerError, err)
 }
 
 // ErrBadGateway error http StatusBadGateway
 func ErrBadGateway(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusBadGateway))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusBadGateway)
-	return err
+	return errStatus(w, r, http.StatusBadGateway, err)
 }
 
 // ErrServiceUnavailable error http StatusServiceUnavailable
 func ErrServiceUnavailable(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusServiceUnavailable))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusServiceUnavailable)
-	return err
+	return errStatus(w, r, http.StatusServiceUnavailable, err)
 }
 
 // ErrGatewayTimeout error http StatusGatewayTimeout
 func ErrGatewayTimeout(w http.ResponseWriter, r *http.Request, err error) error {
-	*r = *r.WithContext(context.WithValue(r.Context(), CtxError, http.StatusGatewayTimeout))
-	w.Header().Set("X-Content-Type-Options", "nosniff")
-	w.WriteHeader(http.StatusGatewayTimeout)
-	return err
+	return errStatus(w, r, http.StatusGatewayTimeout, err)
 }
@@ -0,0 +1,66 @@
+package bifrost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errOrderNotFound = NewError(http.StatusNotFound, "order_not_found", "order does not exist")
+
+func serveAdapter(t *testing.T, a Adapter) (*httptest.ResponseRecorder, Meta) {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	HandlerAdapter(a).ServeHTTP(w, r)
+
+	var resp struct {
+		Meta Meta `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w, resp.Meta
+}
+
+func TestErrorWrapKeepsIdentity(t *testing.T) {
+	cause := errors.New("sql: no rows in result set")
+	err := fmt.Errorf("find order: %w", errOrderNotFound.Wrap(cause))
+
+	assert.True(t, errors.Is(err, errOrderNotFound))
+	assert.True(t, errors.Is(err, cause))
+	assert.Nil(t, errOrderNotFound.Err)
+	assert.Equal(t, "find order: order does not exist: sql: no rows in result set", err.Error())
+}
+
+func TestHandlerAdapterError(t *testing.T) {
+	w, meta := serveAdapter(t, func(w http.ResponseWriter, r *http.Request) error {
+		return errOrderNotFound.WithDetails(ErrorDetail{Field: "order_id", Message: "unknown id"})
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "404", meta.Code)
+	assert.Equal(t, "order_not_found", meta.ErrorCode)
+	assert.Equal(t, "order does not exist", meta.Message)
+	assert.Equal(t, []ErrorDetail{{Field: "order_id", Message: "unknown id"}}, meta.Details)
+}
+
+func TestHandlerAdapterUnknownError(t *testing.T) {
+	w, meta := serveAdapter(t, func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("dial tcp 10.0.0.3:5432: connection refused")
+	})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError), meta.Message)
+}
+
+func TestHandlerAdapterLegacyHelper(t *testing.T) {
+	w, meta := serveAdapter(t, func(w http.ResponseWriter, r *http.Request) error {
+		return ErrBadRequest(w, r, errors.New("name is empty"))
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "400", meta.Code)
+	assert.Equal(t, "name is empty", meta.Message)
+}
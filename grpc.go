@@ -1,11 +1,10 @@
 package bifrost
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
-	"os"
-	"os/signal"
-	"syscall"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -18,6 +17,19 @@ type (
 	GRPCOpts     struct {
 		Port GRPCPort
 		Opts []rpc.ServerOption
+		// Telemetry enables the interceptors tracing, measuring and logging
+		// the rpcs, left out with the error logged when its instruments
+		// cannot be created.
+		Telemetry *GRPCTelemetryOpts
+		// Recovery answers the panics of rpcs with codes.Internal.
+		Recovery bool
+		// Logger logs the server events and is the logger of the rpc
+		// contexts, the global logger of zerolog/log when nil.
+		Logger *zerolog.Logger
+		// TrustRequestID tells whether the request id sent in the metadata
+		// of an rpc, whose peer is in ctx, is kept, any valid one is when
+		// nil. Untrusted ids are replaced by generated ones.
+		TrustRequestID func(ctx context.Context) bool
 	}
 )
 type GRpc struct {
@@ -25,17 +37,42 @@ type GRpc struct {
 	rpcServer *rpc.Server
 	Port      GRPCPort
 	Opts      []rpc.ServerOption
+	logger    zerolog.Logger
 }
 
 func NewServerGRPC(opts GRPCOpts) *GRpc {
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	return &GRpc{rpcServer: rpc.NewServer(opts.Opts...), Port: opts.Port, Opts: opts.Opts}
+	logger := log.Logger
+	if opts.Logger != nil {
+		logger = *opts.Logger
+	}
+	serverOpts := append(requestIDServerOptions(opts.TrustRequestID), loggerServerOptions(logger)...)
+	if opts.Telemetry != nil {
+		telemetry, err := opts.Telemetry.ServerOptions()
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create the grpc telemetry")
+		}
+		serverOpts = append(serverOpts, telemetry...)
+	}
+	if opts.Recovery {
+		serverOpts = append(serverOpts, RecoveryServerOptions()...)
+	}
+	serverOpts = append(serverOpts, opts.Opts...)
+	return &GRpc{errChan: make(chan error, 1), rpcServer: rpc.NewServer(serverOpts...), Port: opts.Port, Opts: serverOpts, logger: logger}
 }
 
+// Run serves the registered services until the process receives SIGINT or SIGTERM.
 func (g *GRpc) Run(callback GRPCCallback) error {
+	ctx, cancel := SignalContext(context.Background())
+	defer cancel()
+	return g.RunContext(ctx, callback)
+}
+
+// RunContext serves the registered services until ctx is cancelled, then
+// stops the server gracefully. It returns the listener error, if any.
+func (g *GRpc) RunContext(ctx context.Context, callback GRPCCallback) error {
 	n, err := net.Listen("tcp", fmt.Sprintf(":%v", g.Port))
 	if err != nil {
-		log.Error().Int("port", int(g.Port)).Err(err).Msg("failed to listen:")
+		g.logger.Error().Int("port", int(g.Port)).Err(err).Msg("failed to listen:")
 		return err
 	}
 	// Description µ micro service
@@ -44,41 +81,38 @@ func (g *GRpc) Run(callback GRPCCallback) error {
 			Welkommen(),
 			g.Port,
 		))
-	log.Info().Msgf("Now serving at %v", g.rpcServer.GetServiceInfo())
 	callback(g.rpcServer)
+	g.logger.Info().Msgf("Now serving at %v", g.rpcServer.GetServiceInfo())
 	go func() {
 		g.errChan <- g.rpcServer.Serve(n)
 	}()
-	g.waitForSignals()
-	g.Stop()
-	return nil
+	return g.wait(ctx)
 }
 
 func (g *GRpc) Stop() {
-	log.Info().Msgf("Stop server at :%d", g.Port)
+	g.logger.Info().Msgf("Stop server at :%d", g.Port)
 	g.rpcServer.Stop()
 }
 
 func (g *GRpc) Quiet() {
-	log.Info().Msg("I have to go...")
-	log.Info().Msg("Stopping server gracefully")
+	g.logger.Info().Msg("I have to go...")
+	g.logger.Info().Msg("Stopping server gracefully")
 	g.rpcServer.GracefulStop()
-	log.Info().Msgf("Stop server at :%d", g.Port)
+	g.logger.Info().Msgf("Stop server at :%d", g.Port)
 }
 
-func (g *GRpc) waitForSignals() {
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
-	for {
-		select {
-		case <-interrupt:
-			g.Quiet()
-			log.Error().Err(fmt.Errorf("interrupt received, shutting down")).Msg("Server interrupted through context")
-			return
-		case err := <-g.errChan:
-			g.Quiet()
-			log.Error().Err(err)
-			return
+func (g *GRpc) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		g.Quiet()
+		g.logger.Info().Err(ctx.Err()).Msg("Server interrupted through context")
+		return nil
+	case err := <-g.errChan:
+		if err == nil || errors.Is(err, rpc.ErrServerStopped) {
+			return nil
 		}
+		g.logger.Error().Err(err).Msg("Server failed")
+		g.Stop()
+		return err
 	}
 }
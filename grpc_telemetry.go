@@ -0,0 +1,239 @@
+package bifrost
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/unit"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	metricRPCServerDuration  = "rpc.server.duration"
+	metricRPCServerRequests  = "rpc.server.requests_per_rpc"
+	metricRPCServerResponses = "rpc.server.responses_per_rpc"
+)
+
+// GRPCTelemetryOpts configures the interceptors tracing, measuring and
+// logging the rpcs of a server.
+type GRPCTelemetryOpts struct {
+	// Propagations are the formats the incoming trace context is read
+	// from, the global propagator of otel when empty.
+	Propagations []Propagation
+	// TracerProvider starts the spans, the global provider of otel when nil.
+	TracerProvider trace.TracerProvider
+	// MeterProvider creates the instruments, the global provider of otel
+	// when nil.
+	MeterProvider metric.MeterProvider
+}
+
+// ServerOptions returns the options chaining the unary and stream
+// interceptors, ahead of any interceptor set afterwards, or the error of
+// the creation of the instruments.
+func (o GRPCTelemetryOpts) ServerOptions() ([]rpc.ServerOption, error) {
+	t, err := newGRPCTelemetry(o)
+	if err != nil {
+		return nil, err
+	}
+	return []rpc.ServerOption{
+		rpc.ChainUnaryInterceptor(t.unary),
+		rpc.ChainStreamInterceptor(t.stream),
+	}, nil
+}
+
+type grpcTelemetry struct {
+	opts       GRPCTelemetryOpts
+	propagator propagation.TextMapPropagator
+	duration   metric.Float64Histogram
+	requests   metric.Int64Histogram
+	responses  metric.Int64Histogram
+}
+
+func newGRPCTelemetry(opts GRPCTelemetryOpts) (*grpcTelemetry, error) {
+	provider := opts.MeterProvider
+	if provider == nil {
+		provider = global.GetMeterProvider()
+	}
+	meter := provider.Meter("grpc.metrics")
+
+	var err error
+	t := &grpcTelemetry{
+		opts:       opts,
+		propagator: TracerOpts{Propagations: opts.Propagations}.propagator(),
+	}
+	if t.duration, err = meter.NewFloat64Histogram(metricRPCServerDuration,
+		metric.WithDescription("Duration of the inbound rpcs"),
+		metric.WithUnit(unit.Milliseconds)); err != nil {
+		return nil, err
+	}
+	if t.requests, err = meter.NewInt64Histogram(metricRPCServerRequests,
+		metric.WithDescription("Number of messages received per rpc")); err != nil {
+		return nil, err
+	}
+	if t.responses, err = meter.NewInt64Histogram(metricRPCServerResponses,
+		metric.WithDescription("Number of messages sent per rpc")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *grpcTelemetry) tracer() trace.Tracer {
+	if t.opts.TracerProvider != nil {
+		return t.opts.TracerProvider.Tracer("grpc.tracer")
+	}
+	return otel.Tracer("grpc.tracer")
+}
+
+// start continues the trace of the incoming metadata with the span of the rpc.
+func (t *grpcTelemetry) start(ctx context.Context, fullMethod string) (context.Context, trace.Span, []attribute.KeyValue) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = t.propagator.Extract(ctx, metadataCarrier(md))
+
+	name := strings.TrimPrefix(fullMethod, "/")
+	service, method := name, ""
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		service, method = name[:idx], name[idx+1:]
+	}
+	attrs := []attribute.KeyValue{
+		semconv.RPCSystemKey.String("grpc"),
+		semconv.RPCServiceKey.String(service),
+		semconv.RPCMethodKey.String(method),
+	}
+	spanAttrs := attrs
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, port, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			spanAttrs = append(attrs[:len(attrs):len(attrs)], semconv.NetPeerIPKey.String(host))
+			if n, err := strconv.Atoi(port); err == nil {
+				spanAttrs = append(spanAttrs, semconv.NetPeerPortKey.Int(n))
+			}
+		}
+	}
+	ctx, span := t.tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(spanAttrs...))
+	ctx = context.WithValue(ctx, TracerContext, span.SpanContext().TraceID().String())
+	return ctx, span, attrs
+}
+
+func (t *grpcTelemetry) end(ctx context.Context, span trace.Span, attrs []attribute.KeyValue, fullMethod string, start time.Time, received int64, sent int64, err error) {
+	elapsed := time.Since(start)
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int64(int64(code)))
+	if code != codes.OK {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+		span.RecordError(err)
+	}
+	span.End()
+
+	attrs = append(attrs[:len(attrs):len(attrs)], semconv.RPCGRPCStatusCodeKey.Int64(int64(code)))
+	t.duration.Record(ctx, float64(elapsed)/float64(time.Millisecond), attrs...)
+	t.requests.Record(ctx, received, attrs...)
+	t.responses.Record(ctx, sent, attrs...)
+
+	l := GetLogger(ctx)
+	var event *zerolog.Event
+	switch code {
+	case codes.OK:
+		event = l.Info()
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unimplemented:
+		event = l.Error().Err(err)
+	default:
+		event = l.Warn().Err(err)
+	}
+	event.
+		Str("path", fullMethod).
+		Int("status", int(code)).
+		Str("code", code.String()).
+		Int64("received", received).
+		Int64("sent", sent).
+		Dur("latency", elapsed).
+		Str("trace_id", span.SpanContext().TraceID().String()).
+		Msg("access")
+}
+
+func (t *grpcTelemetry) unary(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx, span, attrs := t.start(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	sent := int64(0)
+	if err == nil {
+		sent = 1
+	}
+	t.end(ctx, span, attrs, info.FullMethod, start, 1, sent, err)
+	return resp, err
+}
+
+func (t *grpcTelemetry) stream(srv interface{}, ss rpc.ServerStream, info *rpc.StreamServerInfo, handler rpc.StreamHandler) error {
+	start := time.Now()
+	ctx, span, attrs := t.start(ss.Context(), info.FullMethod)
+	ws := &countingStream{ServerStream: ss, ctx: ctx}
+	err := handler(srv, ws)
+	t.end(ctx, span, attrs, info.FullMethod, start, ws.received, ws.sent, err)
+	return err
+}
+
+// countingStream counts the messages of a stream, which runs in the
+// context of its span.
+type countingStream struct {
+	rpc.ServerStream
+	ctx      context.Context
+	received int64
+	sent     int64
+}
+
+func (s *countingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *countingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+	}
+	return err
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+	}
+	return err
+}
+
+// metadataCarrier lets propagators read and write grpc metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) < 1 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,128 @@
+package bifrost
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCTelemetry(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+
+	spans := tracetest.NewInMemoryExporter()
+	metrics, err := NewPrometheusExporter()
+	assert.NoError(t, err)
+
+	port, err := findOpenPort()
+	assert.NoError(t, err)
+	srv := NewServerGRPC(GRPCOpts{
+		Port:   GRPCPort(port),
+		Logger: &logger,
+		Telemetry: &GRPCTelemetryOpts{
+			Propagations:   []Propagation{PropagationTraceContext},
+			TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)),
+			MeterProvider:  metrics.MeterProvider(),
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.RunContext(ctx, func(s *rpc.Server) {
+			healthpb.RegisterHealthServer(s, health.NewServer())
+		})
+	}()
+	waitForPort(t, port)
+
+	conn, err := rpc.Dial(fmt.Sprintf("localhost:%d", port), rpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+	callCtx = metadata.AppendToOutgoingContext(callCtx, "traceparent", "00-"+upstreamTraceID+"-"+upstreamSpanID+"-01")
+	_, err = client.Check(callCtx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = client.Check(callCtx, &healthpb.HealthCheckRequest{Service: "orders"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	watchCtx, watchCancel := context.WithCancel(callCtx)
+	stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.NoError(t, err)
+	watchCancel()
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Eventually(t, func() bool { return len(spans.GetSpans()) == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	ended := spans.GetSpans()
+	for _, span := range ended {
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+		assert.Equal(t, upstreamTraceID, span.SpanContext.TraceID().String())
+		assert.Equal(t, upstreamSpanID, span.Parent.SpanID().String())
+	}
+	assert.Equal(t, "grpc.health.v1.Health/Check", ended[0].Name)
+	assert.Equal(t, "grpc.health.v1.Health/Check", ended[1].Name)
+	assert.Equal(t, "grpc.health.v1.Health/Watch", ended[2].Name)
+	assert.Contains(t, ended[1].Attributes, semconv.RPCGRPCStatusCodeKey.Int64(int64(codes.NotFound)))
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	scraped := w.Body.String()
+	assert.Contains(t, scraped, `rpc_server_duration_count{rpc_grpc_status_code="0",rpc_method="Check",rpc_service="grpc.health.v1.Health",rpc_system="grpc"`)
+	assert.Contains(t, scraped, `rpc_server_duration_count{rpc_grpc_status_code="5",rpc_method="Check",rpc_service="grpc.health.v1.Health",rpc_system="grpc"`)
+	assert.Regexp(t, `rpc_server_responses_per_rpc_sum\{rpc_grpc_status_code="1",rpc_method="Watch",[^}]*\} 1\n`, scraped)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	audits := make([]string, 0)
+	for _, line := range lines {
+		if strings.Contains(line, `"message":"access"`) {
+			audits = append(audits, line)
+		}
+	}
+	assert.Len(t, audits, 3)
+	assert.Contains(t, audits[0], `"level":"info","request_id":"`)
+	assert.Contains(t, audits[0], `"path":"/grpc.health.v1.Health/Check","status":0,"code":"OK"`)
+	assert.Contains(t, audits[1], `"level":"warn"`)
+	assert.Contains(t, audits[1], `"code":"NotFound"`)
+	assert.Contains(t, audits[2], `"trace_id":"`+upstreamTraceID+`"`)
+	assert.Contains(t, audits[2], `"latency":`)
+}
+
+func TestGRPCTelemetryInstrumentError(t *testing.T) {
+	metrics, err := NewPrometheusExporter()
+	assert.NoError(t, err)
+	_, err = metrics.MeterProvider().Meter("grpc.metrics").NewInt64Counter(metricRPCServerDuration)
+	assert.NoError(t, err)
+
+	opts := &GRPCTelemetryOpts{MeterProvider: metrics.MeterProvider()}
+	_, err = opts.ServerOptions()
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	assert.NotPanics(t, func() { NewServerGRPC(GRPCOpts{Telemetry: opts, Logger: &logger}) })
+	assert.Contains(t, buf.String(), `"level":"error"`)
+	assert.Contains(t, buf.String(), metricRPCServerDuration)
+}
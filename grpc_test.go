@@ -1,8 +1,7 @@
 package bifrost
 
 import (
-	"os"
-	"syscall"
+	"context"
 	"testing"
 	"time"
 
@@ -16,21 +15,26 @@ func TestNewServerGRPC(t *testing.T) {
 		assert.Fail(t, "could not find a testing port")
 	}
 	t.Log("Using port", port)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	srv := NewServerGRPC(GRPCOpts{
 		Port: GRPCPort(port),
 	})
-	done := make(chan struct{})
-	// Make sure server exits when receiving TERM signal.
-	go func() {
-		time.Sleep(2 * time.Second)
-		p, err := os.FindProcess(os.Getpid())
-		assert.NoError(t, err)
-		_ = p.Signal(syscall.SIGTERM)
-		done <- struct{}{}
-	}()
+	done := make(chan error, 1)
 
 	// Testing
 	go func() {
-		_ = srv.Run(func(s *rpc.Server) {})
+		done <- srv.RunContext(ctx, func(s *rpc.Server) {})
 	}()
+	waitForPort(t, port)
+
+	// Make sure server exits when the context is cancelled.
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancellation")
+	}
 }
@@ -2,8 +2,9 @@ package bifrost
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -38,6 +40,13 @@ type (
 		TLS      Https
 		CertFile string
 		KeyFile  string
+		// Logger logs the server events and is the logger of the request
+		// contexts, the global logger of zerolog/log when nil.
+		Logger *zerolog.Logger
+		// TrustRequestID tells whether the request id sent in the metadata
+		// of an rpc served by NewServerMultiplex, whose peer is in ctx, is
+		// kept, as GRPCOpts.TrustRequestID does.
+		TrustRequestID func(ctx context.Context) bool
 	}
 )
 
@@ -49,21 +58,35 @@ type Server struct {
 	TLS        Https
 	CertFile   string
 	KeyFile    string
+	logger     zerolog.Logger
 }
 
 func NewServerMux(opts ServeOpts) *Server {
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	logger := log.Logger
+	if opts.Logger != nil {
+		logger = *opts.Logger
+	}
+	baseCtx := WithLogger(context.Background(), logger)
 	return &Server{
+		errChan: make(chan error, 1),
 		httpServer: &http.Server{
 			Addr:         fmt.Sprintf(":%d", opts.Port),
 			ReadTimeout:  time.Duration(opts.TimeOut) * time.Second,
 			WriteTimeout: time.Duration(opts.TimeOut) * time.Second,
-		}, Port: opts.Port, TLS: opts.TLS, CertFile: opts.CertFile, KeyFile: opts.KeyFile, TimeOut: opts.TimeOut}
+			BaseContext:  func(net.Listener) context.Context { return baseCtx },
+		}, Port: opts.Port, TLS: opts.TLS, CertFile: opts.CertFile, KeyFile: opts.KeyFile, TimeOut: opts.TimeOut, logger: logger}
 }
 
+// Run serves handler until the process receives SIGINT or SIGTERM.
 func (s *Server) Run(handler http.Handler) error {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := SignalContext(context.Background())
 	defer cancel()
+	return s.RunContext(ctx, handler)
+}
+
+// RunContext serves handler until ctx is cancelled, then shuts the server down
+// gracefully. It returns the listener error, if any, other than http.ErrServerClosed.
+func (s *Server) RunContext(ctx context.Context, handler http.Handler) error {
 	s.httpServer.Handler = handler
 	// Description µ micro service
 	fmt.Println(
@@ -71,97 +94,131 @@ func (s *Server) Run(handler http.Handler) error {
 			Welkommen(),
 			s.Port,
 		))
-	log.Info().Msgf("Now serving at %s", s.httpServer.Addr)
+	s.logger.Info().Msgf("Now serving at %s", s.httpServer.Addr)
 	go func() {
 		if s.TLS {
-			log.Info().Msg("Secure with HTTPS")
+			s.logger.Info().Msg("Secure with HTTPS")
 			s.errChan <- s.httpServer.ListenAndServeTLS(s.CertFile, s.KeyFile)
 		} else {
 			s.errChan <- s.httpServer.ListenAndServe()
 		}
 	}()
-	s.waitForSignals(ctx)
-	s.Stop()
-	return nil
+	return s.wait(ctx)
 }
 
 func (s *Server) Stop() {
 	if err := s.httpServer.Close(); err != nil {
-		log.Error().Err(err).Msg("Server stopping")
+		s.logger.Error().Err(err).Msg("Server stopping")
 	}
 }
 
 func (s *Server) Quiet(ctx context.Context) {
-	log.Info().Msg("I have to go...")
-	log.Info().Msg("Stopping server gracefully")
+	s.logger.Info().Msg("I have to go...")
+	s.logger.Info().Msg("Stopping server gracefully")
 	if err := s.httpServer.Shutdown(ctx); err != nil {
-		log.Error().Err(err).Msg("Wait is over due to error")
+		s.logger.Error().Err(err).Msg("Wait is over due to error")
 		if err = s.httpServer.Close(); err != nil {
-			log.Error().Err(err).Msg("closing failed")
+			s.logger.Error().Err(err).Msg("closing failed")
 		}
 	}
-	log.Info().Msgf("Stop server at %s", s.httpServer.Addr)
+	s.logger.Info().Msgf("Stop server at %s", s.httpServer.Addr)
 }
 
-func (s *Server) waitForSignals(ctx context.Context) {
-	// Do not make the application hang when it is shutdown.
-	ctxOut, cancel := context.WithTimeout(ctx, time.Duration(s.TimeOut)*time.Second)
-	defer cancel()
-
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
-	for {
-		select {
-		case <-interrupt:
-			s.Quiet(ctxOut)
-			log.Error().Err(fmt.Errorf("interrupt received, shutting down")).Msg("Server interrupted through context")
-			return
-		case err := <-s.errChan:
-			s.Quiet(ctxOut)
-			log.Error().Err(err)
-			return
+func (s *Server) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		// Do not make the application hang when it is shutdown.
+		ctxOut, cancel := context.WithTimeout(context.Background(), time.Duration(s.TimeOut)*time.Second)
+		defer cancel()
+		s.Quiet(ctxOut)
+		s.logger.Info().Err(ctx.Err()).Msg("Server interrupted through context")
+		return nil
+	case err := <-s.errChan:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
 		}
+		s.logger.Error().Err(err).Msg("Server failed")
+		s.Stop()
+		return err
+	}
+}
+
+// SignalContext returns a copy of parent that is cancelled when one of the
+// given signals arrives, by default SIGINT and SIGTERM.
+func SignalContext(parent context.Context, sig ...os.Signal) (context.Context, context.CancelFunc) {
+	if len(sig) < 1 {
+		sig = []os.Signal{os.Interrupt, syscall.SIGTERM}
 	}
+	return signal.NotifyContext(parent, sig...)
 }
 
 type Adapter func(w http.ResponseWriter, r *http.Request) error
 
+// HandlerAdapter turns an Adapter into a http.HandlerFunc. An error returned
+// by the adapter is rendered in the error format of the request, its status
+// taken from the *Error in its chain; any other error is answered as an
+// internal server error.
 func HandlerAdapter(a Adapter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if cType, ok := r.Context().Value(ContentTypeCtxKey).(ContentType); ok {
 			w.Header().Set(HeaderContentType, GetIdxContentType(cType))
 		}
-		if err := a(w, r); err != nil {
-			w.Header().Set("X-Content-Type-Options", "nosniff")
-			code, _ := r.Context().Value(CtxError).(int)
-			null := make(map[string]interface{})
-			resp := &Response{
-				Version: Version{
-					Label:  "v1",
-					Number: "0.1.0",
-				},
-				Meta: Meta{
-					Code:    strconv.Itoa(code),
-					Type:    http.StatusText(code),
-					Message: err.Error(),
-				},
-				Data:       null,
-				Pagination: null,
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		if err := a(ww, r); err != nil {
+			e := adapterError(r, err)
+			if e.StatusCode() >= http.StatusInternalServerError {
+				GetLogger(r.Context()).Error().Err(err).Msg("Handler failed")
 			}
-			if ver, ok := r.Context().Value(CtxVersion).(Version); ok {
-				resp.Version = ver
-			}
-			bytes, err := json.Marshal(resp)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+			// a body already sent, e.g. a stream that failed midway, cannot
+			// be followed by the error envelope
+			if ww.BytesWritten() > 0 {
 				return
 			}
-			_, err = w.Write(bytes)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+			// the legacy Err helpers may have written the status already,
+			// the wrapper then ignores the one written here
+			if err := WriteError(ww, r, e); err != nil {
+				GetLogger(r.Context()).Error().Err(err).Msg("Write error response")
 			}
 			return
 		}
 	}
 }
+
+// ErrorResponse builds the response envelope for e.
+func ErrorResponse(r *http.Request, e *Error) *Response {
+	code := e.StatusCode()
+	null := make(map[string]interface{})
+	resp := &Response{
+		Version: Version{
+			Label:  "v1",
+			Number: "0.1.0",
+		},
+		Meta: Meta{
+			Code:      strconv.Itoa(code),
+			Type:      http.StatusText(code),
+			Message:   e.message(),
+			ErrorCode: e.Code,
+			Details:   e.Details,
+			Warning:   deprecationWarning(r),
+			RequestID: GetRequestID(r.Context()),
+		},
+		Data:       null,
+		Pagination: null,
+	}
+	if ver, ok := r.Context().Value(CtxVersion).(Version); ok {
+		resp.Version = ver
+	}
+	return resp
+}
+
+func adapterError(r *http.Request, err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	// status stored in the context without the matching *Error
+	if code, ok := r.Context().Value(CtxError).(int); ok && code > 0 {
+		return &Error{Status: code, Err: err}
+	}
+	return AsError(err)
+}
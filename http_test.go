@@ -1,14 +1,14 @@
 package bifrost
 
 import (
+	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
 	"net"
 	"net/http"
-	"os"
 	"sync"
-	"syscall"
 	"testing"
 	"time"
 
@@ -43,45 +43,74 @@ func findOpenPort() (int, error) {
 	return 0, fmt.Errorf("could not find port to use for testing (%d attempts)", attempts)
 }
 
+// waitForPort blocks until something accepts connections on port.
+func waitForPort(t *testing.T, port int) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", port))
+		if err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("nothing is listening on port %d", port)
+}
+
 func TestHttpListenAndServe(t *testing.T) {
 	port, err := findOpenPort()
 	if err != nil {
 		assert.Fail(t, "could not find a testing port")
 	}
 	t.Log("Using port", port)
-	//ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	//defer cancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	srv := NewServerMux(ServeOpts{
 		Port:    WebPort(port),
 		TimeOut: WebTimeOut(100),
 	})
-	done := make(chan struct{})
-	// Make sure server exits when receiving TERM signal.
-	go func() {
-		time.Sleep(2 * time.Second)
-		p, err := os.FindProcess(os.Getpid())
-		assert.NoError(t, err)
-		_ = p.Signal(syscall.SIGTERM)
-		done <- struct{}{}
-	}()
+	done := make(chan error, 1)
 
 	// Testing
 	go func() {
-		_ = srv.Run(http.NewServeMux())
+		done <- srv.RunContext(ctx, http.NewServeMux())
 	}()
+	waitForPort(t, port)
 
 	resp, queryErr := http.Get(fmt.Sprintf("http://localhost:%d", port))
-
+	assert.Nil(t, queryErr)
 	if resp != nil {
 		defer func() {
 			_ = resp.Body.Close()
 		}()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 	}
 
-	assert.Nil(t, queryErr)
-	respStatus := resp.StatusCode
-	assert.Equal(t, http.StatusNotFound, respStatus)
+	// Make sure server exits when the context is cancelled.
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancellation")
+	}
+}
+
+func TestHttpListenError(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	srv := NewServerMux(ServeOpts{
+		Port:    WebPort(ln.Addr().(*net.TCPAddr).Port),
+		TimeOut: WebTimeOut(1),
+	})
+	err = srv.RunContext(context.Background(), http.NewServeMux())
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, http.ErrServerClosed))
 }
 
 func TestHttpContentTypeMiddleware(t *testing.T) {
@@ -90,19 +119,13 @@ func TestHttpContentTypeMiddleware(t *testing.T) {
 		assert.Fail(t, "could not find a testing port")
 	}
 	t.Log("Using port", port)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	srv := NewServerMux(ServeOpts{
 		Port:    WebPort(port),
 		TimeOut: WebTimeOut(100),
 	})
-	done := make(chan struct{})
-	// Make sure server exits when receiving TERM signal.
-	go func() {
-		time.Sleep(2 * time.Second)
-		p, err := os.FindProcess(os.Getpid())
-		assert.NoError(t, err)
-		_ = p.Signal(syscall.SIGTERM)
-		done <- struct{}{}
-	}()
 
 	mux := http.NewServeMux()
 	mux.Handle("/", HandlerAdapter(
@@ -115,19 +138,17 @@ func TestHttpContentTypeMiddleware(t *testing.T) {
 
 	// Testing
 	go func() {
-		_ = srv.Run(handler)
+		_ = srv.RunContext(ctx, handler)
 	}()
+	waitForPort(t, port)
 
 	resp, queryErr := http.Get(fmt.Sprintf("http://localhost:%d", port))
-
+	assert.Nil(t, queryErr)
 	if resp != nil {
 		defer func() {
 			_ = resp.Body.Close()
 		}()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, resp.Header.Get(HeaderContentType), MIMEApplicationJSON)
 	}
-
-	assert.Nil(t, queryErr)
-	respStatus := resp.StatusCode
-	assert.Equal(t, http.StatusOK, respStatus)
-	assert.Equal(t, resp.Header.Get(HeaderContentType), MIMEApplicationJSON)
 }
@@ -1,7 +1,6 @@
 package bifrost
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -34,14 +33,51 @@ func RequestJSONBody(r *http.Request, extract interface{}) error {
 
 // ResponseJSONPayload set payload for response http
 func ResponseJSONPayload(w http.ResponseWriter, r *http.Request, code int, responses ...interface{}) error {
-	w.Header().Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
+	return ResponseCodecPayload(w, r, code, MIMEApplicationJSON, responses...)
+}
+
+// ResponseCodecPayload set payload for response http, encoded by the codec
+// registered for mediaType
+func ResponseCodecPayload(w http.ResponseWriter, r *http.Request, code int, mediaType string, responses ...interface{}) error {
+	codec, ok := GetCodec(mediaType)
+	if !ok {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusInternalServerError)
+		return fmt.Errorf("no codec registered for %q", mediaType)
+	}
+	w.Header().Set(HeaderContentType, codec.ContentType())
+	resp, err := newResponse(r, code, responses...)
+	if err != nil {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+
+	b, err := codec.Marshal(resp)
+	if err != nil {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+	w.WriteHeader(code)
+	_, err = w.Write(b)
+	if err != nil {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+	return nil
+}
+
+// newResponse builds the response envelope, merging the responses into its data
+func newResponse(r *http.Request, code int, responses ...interface{}) (*Response, error) {
 	null := make(map[string]interface{})
 	resp := &Response{
 		Version: Version{
 			Label:  "v1",
 			Number: "0.1.0",
 		},
-		Meta:       Meta{Code: http.StatusText(code)},
+		Meta:       Meta{Code: http.StatusText(code), Warning: deprecationWarning(r), RequestID: GetRequestID(r.Context())},
 		Pagination: null,
 	}
 	if ver, ok := r.Context().Value(CtxVersion).(Version); ok {
@@ -70,15 +106,11 @@ func ResponseJSONPayload(w http.ResponseWriter, r *http.Request, code int, respo
 				for n := 0; n < s.Len(); n++ {
 					b, err := json.Marshal(s.Index(n).Interface())
 					if err != nil {
-						w.Header().Set("X-Content-Type-Options", "nosniff")
-						w.WriteHeader(http.StatusInternalServerError)
-						return err
+						return nil, err
 					}
 					tempData := make(map[string]interface{}, 0)
 					if err := json.Unmarshal(b, &tempData); err != nil {
-						w.Header().Set("X-Content-Type-Options", "nosniff")
-						w.WriteHeader(http.StatusInternalServerError)
-						return err
+						return nil, err
 					}
 					dataList = append(dataList, tempData)
 				}
@@ -86,15 +118,11 @@ func ResponseJSONPayload(w http.ResponseWriter, r *http.Request, code int, respo
 			default:
 				b, err := json.Marshal(r)
 				if err != nil {
-					w.Header().Set("X-Content-Type-Options", "nosniff")
-					w.WriteHeader(http.StatusInternalServerError)
-					return err
+					return nil, err
 				}
 				tempData := make(map[string]interface{}, 0)
 				if err := json.Unmarshal(b, &tempData); err != nil {
-					w.Header().Set("X-Content-Type-Options", "nosniff")
-					w.WriteHeader(http.StatusInternalServerError)
-					return err
+					return nil, err
 				}
 				for k, v := range tempData {
 					data[k] = v
@@ -104,21 +132,5 @@ func ResponseJSONPayload(w http.ResponseWriter, r *http.Request, code int, respo
 		}
 	}
 	resp.Data = data
-
-	buf := &bytes.Buffer{}
-	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(true)
-	if err := enc.Encode(resp); err != nil {
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.WriteHeader(http.StatusInternalServerError)
-		return err
-	}
-	w.WriteHeader(code)
-	_, err := w.Write(buf.Bytes())
-	if err != nil {
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.WriteHeader(http.StatusInternalServerError)
-		return err
-	}
-	return nil
+	return resp, nil
 }
@@ -0,0 +1,122 @@
+package bifrost
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+	rpc "google.golang.org/grpc"
+)
+
+var LoggerCtxKey = &ctxRender{"logger"}
+
+// GetLogger returns the logger stored in ctx, the global logger of
+// zerolog/log when there is none.
+func GetLogger(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(LoggerCtxKey).(*zerolog.Logger); ok {
+		return l
+	}
+	return &log.Logger
+}
+
+// WithLogger returns a copy of ctx holding l, which zerolog.Ctx finds too.
+func WithLogger(ctx context.Context, l zerolog.Logger) context.Context {
+	return context.WithValue(l.WithContext(ctx), LoggerCtxKey, &l)
+}
+
+// RequestLogger is a middleware storing a logger for the request in its
+// context, derived from the one already there and enriched with the request
+// id, trace and span ids, method, remote ip and, once routed, the route
+// pattern. Used after HttpTracer, the ids are those of its span.
+func RequestLogger(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		c := GetLogger(r.Context()).With().
+			Str("method", r.Method).
+			Str("remote_ip", remoteIP(r))
+		if id := requestID(r); id != "" {
+			c = c.Str("request_id", id)
+		}
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			c = c.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+		}
+		l := c.Logger()
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			l = l.Hook(routeHook{rctx})
+		}
+		next.ServeHTTP(w, r.WithContext(WithLogger(r.Context(), l)))
+	}
+	return http.HandlerFunc(fn)
+}
+
+// routeHook adds the route pattern chi has matched so far to the events.
+type routeHook struct {
+	rctx *chi.Context
+}
+
+func (h routeHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	if pattern := h.rctx.RoutePattern(); pattern != "" {
+		e.Str("route", pattern)
+	}
+}
+
+// AccessLog is a middleware writing one line per request with the logger of
+// the request context, holding its path, status, bytes written and latency.
+func AccessLog(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		l := GetLogger(r.Context())
+		event := l.Info()
+		if status >= http.StatusInternalServerError {
+			event = l.Error()
+		}
+		event.
+			Str("path", r.URL.Path).
+			Int("status", status).
+			Int("bytes", ww.BytesWritten()).
+			Dur("latency", time.Since(start)).
+			Msg("access")
+	}
+	return http.HandlerFunc(fn)
+}
+
+// loggerServerOptions returns the interceptors storing l, enriched with the
+// request id, in the context of the rpcs.
+func loggerServerOptions(l zerolog.Logger) []rpc.ServerOption {
+	return []rpc.ServerOption{
+		rpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+			return handler(withRPCLogger(ctx, l), req)
+		}),
+		rpc.ChainStreamInterceptor(func(srv interface{}, ss rpc.ServerStream, _ *rpc.StreamServerInfo, handler rpc.StreamHandler) error {
+			return handler(srv, &contextStream{ServerStream: ss, ctx: withRPCLogger(ss.Context(), l)})
+		}),
+	}
+}
+
+func withRPCLogger(ctx context.Context, l zerolog.Logger) context.Context {
+	if id := GetRequestID(ctx); id != "" {
+		l = l.With().Str("request_id", id).Logger()
+	}
+	return WithLogger(ctx, l)
+}
+
+// contextStream replaces the context of a stream.
+type contextStream struct {
+	rpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,177 @@
+package bifrost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	lines := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var fields map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &fields))
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestGetLogger(t *testing.T) {
+	assert.Equal(t, &log.Logger, GetLogger(context.Background()))
+
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), zerolog.New(&buf))
+	GetLogger(ctx).Info().Msg("stored")
+	zerolog.Ctx(ctx).Info().Msg("found by zerolog")
+	assert.Equal(t, "{\"level\":\"info\",\"message\":\"stored\"}\n{\"level\":\"info\",\"message\":\"found by zerolog\"}\n", buf.String())
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	spans := tracetest.NewInMemoryExporter()
+	router := chi.NewRouter()
+	router.Use(
+		func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(WithLogger(r.Context(), zerolog.New(&buf))))
+			})
+		},
+		NewHttpTracer(TracerOpts{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))}),
+		RequestLogger,
+		AccessLog,
+	)
+	router.Get("/orders/{id}", HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		GetLogger(r.Context()).Info().Str("id", chi.URLParam(r, "id")).Msg("order found")
+		return ResponseJSONPayload(w, r, http.StatusOK, map[string]interface{}{"id": 1})
+	}))
+	router.Get("/fail", HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("database down")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	r.Header.Set(HeaderXRequestID, "req-1")
+	r.RemoteAddr = "10.0.0.1:41234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	lines := decodeLogLines(t, &buf)
+	assert.Len(t, lines, 2)
+	span := spans.GetSpans()[0].SpanContext
+	for _, line := range lines {
+		assert.Equal(t, "req-1", line["request_id"])
+		assert.Equal(t, span.TraceID().String(), line["trace_id"])
+		assert.Equal(t, span.SpanID().String(), line["span_id"])
+		assert.Equal(t, http.MethodGet, line["method"])
+		assert.Equal(t, "10.0.0.1", line["remote_ip"])
+		assert.Equal(t, "/orders/{id}", line["route"])
+	}
+	assert.Equal(t, "order found", lines[0]["message"])
+	assert.Equal(t, "7", lines[0]["id"])
+	assert.Equal(t, "access", lines[1]["message"])
+	assert.Equal(t, "info", lines[1]["level"])
+	assert.Equal(t, "/orders/7", lines[1]["path"])
+	assert.Equal(t, float64(http.StatusOK), lines[1]["status"])
+	assert.Equal(t, float64(w.Body.Len()), lines[1]["bytes"])
+	assert.Contains(t, lines[1], "latency")
+
+	buf.Reset()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	lines = decodeLogLines(t, &buf)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "Handler failed", lines[0]["message"])
+	assert.NotContains(t, lines[0], "request_id")
+	assert.Equal(t, "error", lines[1]["level"])
+	assert.Equal(t, float64(http.StatusInternalServerError), lines[1]["status"])
+}
+
+func TestServerMuxLogger(t *testing.T) {
+	global := captureLog(t)
+	format := zerolog.TimeFieldFormat
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	port, err := findOpenPort()
+	assert.NoError(t, err)
+	srv := NewServerMux(ServeOpts{Port: WebPort(port), TimeOut: 5, Logger: &logger})
+	assert.Equal(t, format, zerolog.TimeFieldFormat)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.RunContext(ctx, RequestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			GetLogger(r.Context()).Info().Msg("handled")
+		})))
+	}()
+	waitForPort(t, port)
+
+	res, err := http.Get(fmt.Sprintf("http://localhost:%d/", port))
+	assert.NoError(t, err)
+	res.Body.Close()
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.Empty(t, global.String())
+	assert.Contains(t, buf.String(), `"message":"Now serving at :`)
+	assert.Contains(t, buf.String(), `{"level":"info","method":"GET","remote_ip":"127.0.0.1","message":"handled"}`)
+}
+
+func TestServerGRPCLogger(t *testing.T) {
+	global := captureLog(t)
+	format := zerolog.TimeFieldFormat
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	port, err := findOpenPort()
+	assert.NoError(t, err)
+	srv := NewServerGRPC(GRPCOpts{
+		Port:      GRPCPort(port),
+		Telemetry: &GRPCTelemetryOpts{TracerProvider: sdktrace.NewTracerProvider()},
+		Logger:    &logger,
+	})
+	assert.Equal(t, format, zerolog.TimeFieldFormat)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.RunContext(ctx, func(s *rpc.Server) {
+			healthpb.RegisterHealthServer(s, health.NewServer())
+		})
+	}()
+	waitForPort(t, port)
+
+	conn, err := rpc.Dial(fmt.Sprintf("localhost:%d", port), rpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+	_, err = healthpb.NewHealthClient(conn).Check(callCtx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.Empty(t, global.String())
+	assert.Contains(t, buf.String(), `"path":"/grpc.health.v1.Health/Check","status":0,"code":"OK"`)
+	assert.Contains(t, buf.String(), `"message":"Server interrupted through context"`)
+}
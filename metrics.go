@@ -0,0 +1,217 @@
+package bifrost
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/sdkapi"
+	"go.opentelemetry.io/otel/metric/unit"
+	export "go.opentelemetry.io/otel/sdk/export/metric"
+	"go.opentelemetry.io/otel/sdk/export/metric/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/histogram"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	selector "go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+const (
+	metricServerDuration       = "http.server.duration"
+	metricServerRequestCount   = "http.server.request_count"
+	metricServerActiveRequests = "http.server.active_requests"
+	metricServerRequestSize    = "http.server.request.size"
+	metricServerResponseSize   = "http.server.response.size"
+)
+
+var (
+	// DurationBoundaries are the buckets, in milliseconds, of the duration
+	// histograms exported by NewPrometheusExporter.
+	DurationBoundaries = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	// SizeBoundaries are the buckets, in bytes, of the size histograms
+	// exported by NewPrometheusExporter.
+	SizeBoundaries = []float64{128, 512, 1024, 4096, 16384, 65536, 262144, 1048576}
+)
+
+// MetricsOpts configures the middleware returned by NewHttpMetrics.
+type MetricsOpts struct {
+	// MeterProvider creates the instruments, the global provider of otel
+	// when nil.
+	MeterProvider metric.MeterProvider
+	// Filters tell whether a request is measured, it is when all of them
+	// return true.
+	Filters []func(r *http.Request) bool
+	// Hosts are the values of the Host header kept in the http.host label,
+	// the others are counted as "other". The client sets the header, the
+	// label is left out when Hosts is empty to bound the series.
+	Hosts []string
+}
+
+type httpMetrics struct {
+	duration     metric.Float64Histogram
+	requests     metric.Int64Counter
+	active       metric.Int64UpDownCounter
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+}
+
+// NewHttpMetrics returns a middleware recording the count, duration, sizes
+// and number in flight of the requests. They are labelled with the semconv
+// http attributes, the route being the pattern chi matched rather than the
+// path, and the host one of opts.Hosts.
+func NewHttpMetrics(opts MetricsOpts) (func(next http.Handler) http.Handler, error) {
+	provider := opts.MeterProvider
+	if provider == nil {
+		provider = global.GetMeterProvider()
+	}
+	meter := provider.Meter("http.metrics")
+
+	var (
+		m   httpMetrics
+		err error
+	)
+	if m.duration, err = meter.NewFloat64Histogram(metricServerDuration,
+		metric.WithDescription("Duration of the inbound requests"),
+		metric.WithUnit(unit.Milliseconds)); err != nil {
+		return nil, err
+	}
+	if m.requests, err = meter.NewInt64Counter(metricServerRequestCount,
+		metric.WithDescription("Number of the inbound requests")); err != nil {
+		return nil, err
+	}
+	if m.active, err = meter.NewInt64UpDownCounter(metricServerActiveRequests,
+		metric.WithDescription("Number of the inbound requests in flight")); err != nil {
+		return nil, err
+	}
+	if m.requestSize, err = meter.NewInt64Histogram(metricServerRequestSize,
+		metric.WithDescription("Size of the inbound request bodies"),
+		metric.WithUnit(unit.Bytes)); err != nil {
+		return nil, err
+	}
+	if m.responseSize, err = meter.NewInt64Histogram(metricServerResponseSize,
+		metric.WithDescription("Size of the response bodies"),
+		metric.WithUnit(unit.Bytes)); err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			for _, filter := range opts.Filters {
+				if !filter(r) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			ctx := r.Context()
+			attrs := opts.attributes(r)
+			m.active.Add(ctx, 1, attrs...)
+			defer m.active.Add(ctx, -1, attrs...)
+
+			start := time.Now()
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			m.record(ctx, r, ww, body.n, time.Since(start), attrs)
+		}
+		return http.HandlerFunc(fn)
+	}, nil
+}
+
+// attributes are the semconv attributes of r, its host mapped to Hosts.
+func (o MetricsOpts) attributes(r *http.Request) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, 6)
+	for _, attr := range semconv.HTTPServerMetricAttributesFromHTTPRequest("", r) {
+		if attr.Key != semconv.HTTPHostKey {
+			attrs = append(attrs, attr)
+		}
+	}
+	if len(o.Hosts) > 0 {
+		host := "other"
+		for _, h := range o.Hosts {
+			if strings.EqualFold(h, r.Host) {
+				host = h
+				break
+			}
+		}
+		attrs = append(attrs, semconv.HTTPHostKey.String(host))
+	}
+	return append(attrs, semconv.HTTPMethodKey.String(r.Method))
+}
+
+func (m httpMetrics) record(ctx context.Context, r *http.Request, ww middleware.WrapResponseWriter, requestSize int64, elapsed time.Duration, attrs []attribute.KeyValue) {
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	// the sdk sorts attributes in place, those of active must stay apart
+	attrs = append(attrs[:len(attrs):len(attrs)], semconv.HTTPStatusCodeKey.Int(status))
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			attrs = append(attrs, semconv.HTTPRouteKey.String(pattern))
+		}
+	}
+	m.requests.Add(ctx, 1, attrs...)
+	m.duration.Record(ctx, float64(elapsed)/float64(time.Millisecond), attrs...)
+	m.requestSize.Record(ctx, requestSize, attrs...)
+	m.responseSize.Record(ctx, int64(ww.BytesWritten()), attrs...)
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewPrometheusExporter returns an exporter serving the metrics of its
+// MeterProvider in the Prometheus text format, to be routed as /metrics.
+// Histograms measured in bytes use the SizeBoundaries, the others the
+// DurationBoundaries.
+//
+//	exporter, err := bifrost.NewPrometheusExporter()
+//	metrics, err := bifrost.NewHttpMetrics(bifrost.MetricsOpts{MeterProvider: exporter.MeterProvider()})
+//	router.Use(metrics)
+//	router.Handle("/metrics", exporter)
+func NewPrometheusExporter() (*prometheus.Exporter, error) {
+	c := controller.New(
+		processor.NewFactory(
+			boundariesSelector{
+				duration: selector.NewWithHistogramDistribution(histogram.WithExplicitBoundaries(DurationBoundaries)),
+				size:     selector.NewWithHistogramDistribution(histogram.WithExplicitBoundaries(SizeBoundaries)),
+			},
+			aggregation.CumulativeTemporalitySelector(),
+			processor.WithMemory(true),
+		),
+	)
+	return prometheus.New(prometheus.Config{}, c)
+}
+
+// boundariesSelector picks the histogram buckets after the instrument unit.
+type boundariesSelector struct {
+	duration export.AggregatorSelector
+	size     export.AggregatorSelector
+}
+
+func (s boundariesSelector) AggregatorFor(descriptor *sdkapi.Descriptor, aggregators ...*export.Aggregator) {
+	if descriptor.Unit() == unit.Bytes {
+		s.size.AggregatorFor(descriptor, aggregators...)
+		return
+	}
+	s.duration.AggregatorFor(descriptor, aggregators...)
+}
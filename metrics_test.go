@@ -0,0 +1,87 @@
+package bifrost
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpMetrics(t *testing.T) {
+	exporter, err := NewPrometheusExporter()
+	assert.NoError(t, err)
+	metrics, err := NewHttpMetrics(MetricsOpts{
+		MeterProvider: exporter.MeterProvider(),
+		Filters:       []func(r *http.Request) bool{SkipPaths("/metrics")},
+	})
+	assert.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(metrics)
+	router.Handle("/metrics", exporter)
+	router.Post("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	})
+
+	for _, id := range []string{"1", "2", "3"} {
+		r := httptest.NewRequest(http.MethodPost, "/orders/"+id, strings.NewReader(`{"qty":2}`))
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	scraped := w.Body.String()
+
+	labels := `http_flavor="1.1",http_method="POST",http_route="/orders/{id}",http_scheme="http",http_status_code="201"`
+	sample := func(name string, labels string, le string, value string) {
+		for _, line := range strings.Split(scraped, "\n") {
+			if strings.HasPrefix(line, name+"{"+labels+",") &&
+				strings.HasSuffix(line, le+"} "+value) {
+				return
+			}
+		}
+		t.Errorf("no sample %s{%s} %s", name, labels, value)
+	}
+	sample("http_server_request_count", labels, "", "3")
+	sample("http_server_duration_count", labels, "", "3")
+	sample("http_server_duration_bucket", labels, `le="10000"`, "3")
+	sample("http_server_request_size_bucket", labels, `le="128"`, "3")
+	sample("http_server_request_size_sum", labels, "", "27")
+	sample("http_server_response_size_sum", labels, "", "24")
+	sample("http_server_request_count", `http_flavor="1.1",http_method="GET",http_scheme="http",http_status_code="404"`, "", "1")
+	sample("http_server_active_requests", `http_flavor="1.1",http_method="POST",http_scheme="http"`, "", "0")
+	assert.NotContains(t, scraped, "/orders/1")
+	assert.NotContains(t, scraped, "/metrics")
+}
+
+func TestHttpMetricsHosts(t *testing.T) {
+	exporter, err := NewPrometheusExporter()
+	assert.NoError(t, err)
+	metrics, err := NewHttpMetrics(MetricsOpts{
+		MeterProvider: exporter.MeterProvider(),
+		Hosts:         []string{"api.example.com"},
+	})
+	assert.NoError(t, err)
+	handler := metrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, host := range []string{"API.example.com", "a.attacker.test", "b.attacker.test"} {
+		r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		r.Host = host
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	w := httptest.NewRecorder()
+	exporter.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	scraped := w.Body.String()
+	assert.Regexp(t, `http_server_request_count\{http_flavor="1.1",http_host="api.example.com",http_method="GET",[^}]*\} 1\n`, scraped)
+	assert.Regexp(t, `http_server_request_count\{http_flavor="1.1",http_host="other",http_method="GET",[^}]*\} 2\n`, scraped)
+	assert.NotContains(t, scraped, "attacker")
+}
@@ -0,0 +1,103 @@
+package bifrost
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	rpc "google.golang.org/grpc"
+)
+
+// Multiplex serves http and grpc on a single port. HTTP/2 requests with a
+// grpc content-type go to the grpc server, everything else to the http handler.
+type Multiplex struct {
+	server    *Server
+	rpcServer *rpc.Server
+	h2s       *http2.Server
+}
+
+// NewServerMultiplex listens on opts.Port, with TLS when opts.TLS is set and
+// cleartext HTTP/2 (h2c) otherwise. The read and write timeouts of opts apply
+// to the HTTP/1 requests only: the HTTP/2 streams, grpc ones among them, may
+// outlive them and are bounded by their contexts.
+func NewServerMultiplex(opts ServeOpts, rpcOpts ...rpc.ServerOption) *Multiplex {
+	server := NewServerMux(opts)
+	serverOpts := append(requestIDServerOptions(opts.TrustRequestID), loggerServerOptions(server.logger)...)
+	m := &Multiplex{
+		server:    server,
+		rpcServer: rpc.NewServer(append(serverOpts, rpcOpts...)...),
+		h2s:       &http2.Server{},
+	}
+	// let Shutdown send GOAWAY to HTTP/2 connections, h2c ones included
+	if err := http2.ConfigureServer(m.server.httpServer, m.h2s); err != nil {
+		m.server.logger.Error().Err(err).Msg("failed to configure http2")
+		return m
+	}
+	// over TLS the write timeout would reset each stream once elapsed,
+	// h2c connections are hijacked without deadlines
+	serveH2 := m.server.httpServer.TLSNextProto[http2.NextProtoTLS]
+	m.server.httpServer.TLSNextProto[http2.NextProtoTLS] = func(hs *http.Server, c *tls.Conn, h http.Handler) {
+		_ = c.SetWriteDeadline(time.Time{})
+		serveH2(&http.Server{
+			ReadTimeout:    hs.ReadTimeout,
+			IdleTimeout:    hs.IdleTimeout,
+			MaxHeaderBytes: hs.MaxHeaderBytes,
+			ConnState:      hs.ConnState,
+			ErrorLog:       hs.ErrorLog,
+		}, c, h)
+	}
+	return m
+}
+
+// Handler routes grpc calls to the grpc server and the rest to handler.
+func (m *Multiplex) Handler(handler http.Handler) http.Handler {
+	mixed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get(HeaderContentType), MIMEApplicationGRPC) {
+			m.rpcServer.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	if m.server.TLS {
+		return mixed
+	}
+	return h2c.NewHandler(mixed, m.h2s)
+}
+
+// Run serves both protocols until the process receives SIGINT or SIGTERM.
+func (m *Multiplex) Run(handler http.Handler, callback GRPCCallback) error {
+	ctx, cancel := SignalContext(context.Background())
+	defer cancel()
+	return m.RunContext(ctx, handler, callback)
+}
+
+// RunContext serves both protocols until ctx is cancelled, then shuts them
+// down gracefully. It returns the listener error, if any.
+func (m *Multiplex) RunContext(ctx context.Context, handler http.Handler, callback GRPCCallback) error {
+	callback(m.rpcServer)
+	m.server.logger.Info().Msgf("Now serving grpc %v", m.rpcServer.GetServiceInfo())
+	// the http server has drained by now, close the streams still left
+	defer m.rpcServer.Stop()
+	return m.server.RunContext(ctx, m.Handler(handler))
+}
+
+// Process wraps the multiplexed server for a Supervisor.
+func (m *Multiplex) Process(handler http.Handler, callback GRPCCallback) Process {
+	return func(ctx context.Context) error {
+		return m.RunContext(ctx, handler, callback)
+	}
+}
+
+func (m *Multiplex) Stop() {
+	m.rpcServer.Stop()
+	m.server.Stop()
+}
+
+func (m *Multiplex) Quiet(ctx context.Context) {
+	m.server.Quiet(ctx)
+	m.rpcServer.Stop()
+}
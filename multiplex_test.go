@@ -0,0 +1,206 @@
+package bifrost
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeSelfSignedCert writes a localhost certificate and key into dir, for
+// server authentication unless other usages are given. The certificate is
+// its own authority, to be trusted as is.
+func writeSelfSignedCert(t *testing.T, dir string, usages ...x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	if len(usages) == 0 {
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  usages,
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func runMultiplex(t *testing.T, opts ServeOpts) (context.CancelFunc, chan error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServerMultiplex(opts)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "hello")
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.RunContext(ctx, mux, func(s *rpc.Server) {
+			healthpb.RegisterHealthServer(s, health.NewServer())
+		})
+	}()
+	waitForPort(t, int(opts.Port))
+	return cancel, done
+}
+
+func checkHealth(t *testing.T, conn *rpc.ClientConn) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	if resp != nil {
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	}
+}
+
+func TestMultiplexCleartext(t *testing.T) {
+	port, err := findOpenPort()
+	if err != nil {
+		assert.Fail(t, "could not find a testing port")
+	}
+	cancel, done := runMultiplex(t, ServeOpts{Port: WebPort(port), TimeOut: WebTimeOut(1)})
+	defer cancel()
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d", port))
+	assert.NoError(t, err)
+	if resp != nil {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	conn, err := rpc.Dial(fmt.Sprintf("localhost:%d", port), rpc.WithInsecure())
+	assert.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	checkHealth(t, conn)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestMultiplexTLS(t *testing.T) {
+	port, err := findOpenPort()
+	if err != nil {
+		assert.Fail(t, "could not find a testing port")
+	}
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+	cancel, done := runMultiplex(t, ServeOpts{
+		Port:     WebPort(port),
+		TimeOut:  WebTimeOut(1),
+		TLS:      true,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	})
+	defer cancel()
+
+	// #nosec
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	client := http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Get(fmt.Sprintf("https://localhost:%d", port))
+	assert.NoError(t, err)
+	if resp != nil {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	conn, err := rpc.Dial(fmt.Sprintf("localhost:%d", port), rpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	assert.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	checkHealth(t, conn)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestMultiplexLongStream(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t, t.TempDir())
+	// #nosec
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	for name, tc := range map[string]struct {
+		opts ServeOpts
+		dial rpc.DialOption
+	}{
+		"cleartext": {ServeOpts{}, rpc.WithInsecure()},
+		"tls":       {ServeOpts{TLS: true, CertFile: certFile, KeyFile: keyFile}, rpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			port, err := findOpenPort()
+			assert.NoError(t, err)
+			opts := tc.opts
+			opts.Port, opts.TimeOut = WebPort(port), WebTimeOut(1)
+			srv := NewServerMultiplex(opts)
+			healthServer := health.NewServer()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() {
+				done <- srv.RunContext(ctx, http.NewServeMux(), func(s *rpc.Server) {
+					healthpb.RegisterHealthServer(s, healthServer)
+				})
+			}()
+			waitForPort(t, port)
+
+			conn, err := rpc.Dial(fmt.Sprintf("localhost:%d", port), tc.dial)
+			assert.NoError(t, err)
+			defer conn.Close()
+			watchCtx, watchCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer watchCancel()
+			stream, err := healthpb.NewHealthClient(conn).Watch(watchCtx, &healthpb.HealthCheckRequest{Service: "orders"})
+			assert.NoError(t, err)
+			resp, err := stream.Recv()
+			assert.NoError(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVICE_UNKNOWN, resp.GetStatus())
+
+			// the stream outlives the read and write timeouts of the server
+			time.Sleep(1500 * time.Millisecond)
+			healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+			resp, err = stream.Recv()
+			assert.NoError(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+			checkHealth(t, conn)
+
+			watchCancel()
+			cancel()
+			assert.NoError(t, <-done)
+		})
+	}
+}
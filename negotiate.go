@@ -0,0 +1,154 @@
+package bifrost
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	OffersCtxKey = &ctxRender{"offers"}
+
+	// DefaultOffers are the media types Respond encodes when the route has
+	// not declared its own with Produces.
+	DefaultOffers = []string{MIMEApplicationJSON}
+)
+
+// MediaRange is one entry of an Accept header.
+type MediaRange struct {
+	Type    string
+	Params  map[string]string
+	Quality float64
+}
+
+// specificity ranks exact types above type/* and */*.
+func (m MediaRange) specificity() int {
+	switch {
+	case m.Type == "*/*":
+		return 0
+	case strings.HasSuffix(m.Type, "/*"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (m MediaRange) matches(mediaType string) bool {
+	switch m.specificity() {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(m.Type, "*"))
+	default:
+		return m.Type == mediaType
+	}
+}
+
+// ParseAccept parses an Accept header into its media ranges, the most
+// preferred first. Malformed entries are skipped.
+func ParseAccept(accept string) []MediaRange {
+	ranges := make([]MediaRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+			delete(params, "q")
+		}
+		ranges = append(ranges, MediaRange{Type: mediaType, Params: params, Quality: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].Quality != ranges[j].Quality {
+			return ranges[i].Quality > ranges[j].Quality
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// Negotiate returns the offer the Accept header prefers, or "" when none of
+// them is acceptable. Each offer takes the quality of the most specific range
+// matching it, ties go to the first offer. An empty Accept accepts anything.
+func Negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		if len(offers) < 1 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := ParseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		mediaType, _, err := mime.ParseMediaType(offer)
+		if err != nil {
+			continue
+		}
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			if mr.matches(mediaType) && mr.specificity() > specificity {
+				q, specificity = mr.Quality, mr.specificity()
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Produces is a middleware that declares the media types Respond may encode
+// for the route, in order of preference.
+func Produces(mediaTypes ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), OffersCtxKey, mediaTypes))
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// GetOffers returns the media types the route produces.
+func GetOffers(r *http.Request) []string {
+	if offers, ok := r.Context().Value(OffersCtxKey).([]string); ok && len(offers) > 0 {
+		return offers
+	}
+	return DefaultOffers
+}
+
+// Respond writes the response envelope encoded in the media type the client
+// accepts best among those the route produces. When none is acceptable it
+// answers 406 through ErrNotAcceptable.
+func Respond(w http.ResponseWriter, r *http.Request, code int, responses ...interface{}) error {
+	addVary(w, HeaderAccept)
+	offers := GetOffers(r)
+	mediaType := Negotiate(r.Header.Get(HeaderAccept), offers...)
+	if mediaType == "" {
+		return ErrNotAcceptable(w, r, fmt.Errorf("acceptable media types are %s", strings.Join(offers, ", ")))
+	}
+	return ResponseCodecPayload(w, r, code, mediaType, responses...)
+}
+
+func addVary(w http.ResponseWriter, header string) {
+	for _, v := range w.Header().Values("Vary") {
+		for _, h := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(h), header) {
+				return
+			}
+		}
+	}
+	w.Header().Add("Vary", header)
+}
@@ -0,0 +1,75 @@
+package bifrost
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccept(t *testing.T) {
+	ranges := ParseAccept("text/*;q=0.3, text/html;q=0.7, text/html;level=1, */*;q=0.5, bogus")
+	types := make([]string, 0)
+	for _, mr := range ranges {
+		types = append(types, mr.Type)
+	}
+	assert.Equal(t, []string{"text/html", "text/html", "*/*", "text/*"}, types)
+	assert.Equal(t, map[string]string{"level": "1"}, ranges[0].Params)
+	assert.Equal(t, 0.7, ranges[1].Quality)
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{MIMEApplicationJSON, MIMEApplicationXML, MIMEApplicationMsgpack}
+	cases := []struct {
+		accept   string
+		expected string
+	}{
+		{"", MIMEApplicationJSON},
+		{"*/*", MIMEApplicationJSON},
+		{"application/xml", MIMEApplicationXML},
+		{"application/json;q=0.5, application/xml", MIMEApplicationXML},
+		{"application/*;q=0.2, application/msgpack;q=0.9", MIMEApplicationMsgpack},
+		{"*/*;q=0.8, application/json;q=0", MIMEApplicationXML},
+		{"text/html", ""},
+	}
+	for _, tt := range cases {
+		assert.Equal(t, tt.expected, Negotiate(tt.accept, offers...), tt.accept)
+	}
+}
+
+func respondTo(t *testing.T, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	r.Header.Set(HeaderAccept, accept)
+	w := httptest.NewRecorder()
+	handler := Produces(MIMEApplicationJSON, MIMEApplicationXML)(HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		return Respond(w, r, http.StatusOK, map[string]interface{}{"message": "ok"})
+	}))
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestRespond(t *testing.T) {
+	w := respondTo(t, "application/xml;q=0.9, application/json;q=0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MIMEApplicationXMLCharsetUTF8, w.Header().Get(HeaderContentType))
+	assert.Equal(t, HeaderAccept, w.Header().Get("Vary"))
+
+	w = respondTo(t, "")
+	assert.Equal(t, MIMEApplicationJSONCharsetUTF8, w.Header().Get(HeaderContentType))
+}
+
+func TestRespondNotAcceptable(t *testing.T) {
+	w := respondTo(t, "application/msgpack")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, HeaderAccept, w.Header().Get("Vary"))
+
+	var resp struct {
+		Meta Meta `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "acceptable media types are application/json, application/xml", resp.Meta.Message)
+}
@@ -0,0 +1,289 @@
+package bifrost
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	pageParamLimit  = "limit"
+	pageParamPage   = "page"
+	pageParamOffset = "offset"
+	pageParamCursor = "cursor"
+)
+
+var (
+	// DefaultPagination fills the limits a PaginationOpts leaves to zero.
+	DefaultPagination = PaginationOpts{DefaultLimit: 10, MaxLimit: 100}
+
+	// ErrInvalidCursor is answered for a cursor which is malformed or whose
+	// signature does not match.
+	ErrInvalidCursor = NewError(http.StatusBadRequest, "invalid_cursor", "invalid pagination cursor")
+
+	errCursorSecret = errors.New("bifrost: pagination cursors need a secret")
+)
+
+// PaginationOpts configures how ParsePage reads the pagination parameters.
+type PaginationOpts struct {
+	// DefaultLimit is used when the request has no limit.
+	DefaultLimit int
+	// MaxLimit caps the limit a request may ask for.
+	MaxLimit int
+	// Secret is the HMAC key signing the cursors, they can neither be
+	// encoded nor decoded without it.
+	Secret []byte
+}
+
+func (o PaginationOpts) limit() int {
+	if o.DefaultLimit > 0 {
+		return o.DefaultLimit
+	}
+	return DefaultPagination.DefaultLimit
+}
+
+func (o PaginationOpts) maxLimit() int {
+	if o.MaxLimit > 0 {
+		return o.MaxLimit
+	}
+	return DefaultPagination.MaxLimit
+}
+
+// EncodeCursor encodes v as json in an opaque token, signed so that a
+// client cannot forge or alter it.
+func (o PaginationOpts) EncodeCursor(v interface{}) (string, error) {
+	if len(o.Secret) < 1 {
+		return "", errCursorSecret
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b) + "." +
+		base64.RawURLEncoding.EncodeToString(o.sign(b)), nil
+}
+
+// DecodeCursor verifies the signature of token and decodes its value into v.
+func (o PaginationOpts) DecodeCursor(token string, v interface{}) error {
+	b, err := o.verify(token)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor.Wrap(err)
+	}
+	return nil
+}
+
+func (o PaginationOpts) sign(b []byte) []byte {
+	mac := hmac.New(sha256.New, o.Secret)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+func (o PaginationOpts) verify(token string) ([]byte, error) {
+	if len(o.Secret) < 1 {
+		return nil, errCursorSecret
+	}
+	idx := strings.LastIndex(token, ".")
+	if idx < 0 {
+		return nil, ErrInvalidCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token[:idx])
+	if err != nil {
+		return nil, ErrInvalidCursor.Wrap(err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(token[idx+1:])
+	if err != nil {
+		return nil, ErrInvalidCursor.Wrap(err)
+	}
+	if !hmac.Equal(sig, o.sign(b)) {
+		return nil, ErrInvalidCursor
+	}
+	return b, nil
+}
+
+// PageRequest is the page a request asks for.
+type PageRequest struct {
+	Limit int
+	// Page is the page number starting at 1, zero when the request paginates
+	// by offset or cursor.
+	Page   int
+	Offset int
+	// Cursor is the verified cursor token, empty on the first page.
+	Cursor string
+
+	opts PaginationOpts
+}
+
+// ParsePage reads the limit, page, offset and cursor query parameters of r.
+// A page number is turned into the matching offset, and a limit above the
+// maximum is lowered to it. Only one of page, offset and cursor may be given.
+// Invalid parameters are answered with ErrBindParameter, cursors which do
+// not verify with ErrInvalidCursor.
+func ParsePage(r *http.Request, opts PaginationOpts) (PageRequest, error) {
+	q := r.URL.Query()
+	p := PageRequest{Limit: opts.limit(), opts: opts}
+
+	invalid := func(key string) error {
+		return ErrBindParameter.WithDetails(ErrorDetail{
+			Field:   key,
+			Code:    BindQuery,
+			Message: fmt.Sprintf("invalid %s parameter %q", BindQuery, q.Get(key)),
+		})
+	}
+	atoi := func(key string, min int) (int, error) {
+		n, err := strconv.Atoi(q.Get(key))
+		if err != nil || n < min {
+			return 0, invalid(key)
+		}
+		return n, nil
+	}
+
+	given := make([]string, 0, 3)
+	for _, key := range []string{pageParamPage, pageParamOffset, pageParamCursor} {
+		if q.Get(key) != "" {
+			given = append(given, key)
+		}
+	}
+	if len(given) > 1 {
+		return p, ErrBindParameter.WithDetails(ErrorDetail{
+			Field:   given[1],
+			Code:    BindQuery,
+			Message: fmt.Sprintf("%s cannot be combined with %s", given[1], given[0]),
+		})
+	}
+
+	var err error
+	if q.Get(pageParamLimit) != "" {
+		if p.Limit, err = atoi(pageParamLimit, 1); err != nil {
+			return p, err
+		}
+		if p.Limit > opts.maxLimit() {
+			p.Limit = opts.maxLimit()
+		}
+	}
+	switch {
+	case q.Get(pageParamPage) != "":
+		if p.Page, err = atoi(pageParamPage, 1); err != nil {
+			return p, err
+		}
+		// the offset of the next page has to fit an int too
+		if p.Page > math.MaxInt/p.Limit {
+			return p, invalid(pageParamPage)
+		}
+		p.Offset = (p.Page - 1) * p.Limit
+	case q.Get(pageParamOffset) != "":
+		if p.Offset, err = atoi(pageParamOffset, 0); err != nil {
+			return p, err
+		}
+		if p.Offset > math.MaxInt-p.Limit {
+			return p, invalid(pageParamOffset)
+		}
+	case q.Get(pageParamCursor) != "":
+		if _, err = opts.verify(q.Get(pageParamCursor)); err != nil {
+			return p, err
+		}
+		p.Cursor = q.Get(pageParamCursor)
+	}
+	return p, nil
+}
+
+// DecodeCursor decodes the value of the request cursor into v.
+func (p PageRequest) DecodeCursor(v interface{}) error {
+	return p.opts.DecodeCursor(p.Cursor, v)
+}
+
+// OffsetPagination returns the pagination of an offset or page-number page
+// holding count items out of total, a negative total when it is unknown, and
+// adds the matching first, prev, next and last Link headers to w.
+func (p PageRequest) OffsetPagination(w http.ResponseWriter, r *http.Request, count int, total int64) Pagination {
+	hasMore := count >= p.Limit
+	pagination := Pagination{Limit: p.Limit, Page: p.Page, Offset: p.Offset, HasMore: &hasMore}
+	if total >= 0 {
+		hasMore = int64(p.Offset+count) < total
+		pagination.Total = &total
+	}
+
+	param, position := pageParamOffset, func(offset int) string {
+		return strconv.Itoa(offset)
+	}
+	if p.Page > 0 {
+		param, position = pageParamPage, func(offset int) string {
+			return strconv.Itoa(offset/p.Limit + 1)
+		}
+	}
+	link := func(rel string, offset int) {
+		addPageLink(w, r, rel, map[string]string{
+			pageParamLimit: strconv.Itoa(p.Limit),
+			param:          position(offset),
+		})
+	}
+	link("first", 0)
+	if p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		link("prev", prev)
+	}
+	if hasMore {
+		link("next", p.Offset+p.Limit)
+	}
+	if total > 0 {
+		link("last", int((total-1)/int64(p.Limit))*p.Limit)
+	}
+	return pagination
+}
+
+// CursorPagination returns the pagination of a cursor page, next and prev
+// being the positions of the neighbouring pages or nil when there is none.
+// They are signed as cursors and linked from the next and prev Link headers.
+func (p PageRequest) CursorPagination(w http.ResponseWriter, r *http.Request, next interface{}, prev interface{}) (Pagination, error) {
+	hasMore := next != nil
+	pagination := Pagination{Limit: p.Limit, HasMore: &hasMore}
+	for _, c := range []struct {
+		rel    string
+		value  interface{}
+		cursor *interface{}
+	}{
+		{"prev", prev, &pagination.PrevCursor},
+		{"next", next, &pagination.NextCursor},
+	} {
+		if c.value == nil {
+			continue
+		}
+		token, err := p.opts.EncodeCursor(c.value)
+		if err != nil {
+			return pagination, err
+		}
+		*c.cursor = token
+		addPageLink(w, r, c.rel, map[string]string{
+			pageParamLimit:  strconv.Itoa(p.Limit),
+			pageParamCursor: token,
+		})
+	}
+	return pagination, nil
+}
+
+// addPageLink adds an RFC 8288 Link to the request URL with the pagination
+// parameters replaced by params.
+func addPageLink(w http.ResponseWriter, r *http.Request, rel string, params map[string]string) {
+	u := *r.URL
+	q := u.Query()
+	for _, key := range []string{pageParamPage, pageParamOffset, pageParamCursor} {
+		q.Del(key)
+	}
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	w.Header().Add(HeaderLink, fmt.Sprintf("<%s>; rel=%q", u.String(), rel))
+}
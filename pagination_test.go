@@ -0,0 +1,151 @@
+package bifrost
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pageOpts = PaginationOpts{DefaultLimit: 20, MaxLimit: 50, Secret: []byte("s3cr3t")}
+
+func TestParsePage(t *testing.T) {
+	cursor, err := pageOpts.EncodeCursor(map[string]interface{}{"id": 42})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		query    string
+		expected PageRequest
+		code     string
+	}{
+		{"", PageRequest{Limit: 20}, ""},
+		{"limit=5&page=3", PageRequest{Limit: 5, Page: 3, Offset: 10}, ""},
+		{"limit=500&offset=7", PageRequest{Limit: 50, Offset: 7}, ""},
+		{"cursor=" + cursor, PageRequest{Limit: 20, Cursor: cursor}, ""},
+		{"limit=0", PageRequest{}, "invalid_parameter"},
+		{"page=zero", PageRequest{}, "invalid_parameter"},
+		{"offset=-1", PageRequest{}, "invalid_parameter"},
+		{"page=2&offset=10", PageRequest{}, "invalid_parameter"},
+		{"page=9223372036854775807&limit=10", PageRequest{}, "invalid_parameter"},
+		{"page=461168601842738791&limit=20", PageRequest{}, "invalid_parameter"},
+		{"page=461168601842738790&limit=20", PageRequest{Limit: 20, Page: 461168601842738790, Offset: 9223372036854775780}, ""},
+		{"offset=9223372036854775800", PageRequest{}, "invalid_parameter"},
+		{"cursor=" + cursor + "x", PageRequest{}, "invalid_cursor"},
+		{"cursor=bm9wZQ", PageRequest{}, "invalid_cursor"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil)
+			p, err := ParsePage(r, pageOpts)
+			if tt.code != "" {
+				assert.Equal(t, tt.code, AsError(err).Code)
+				assert.Equal(t, http.StatusBadRequest, AsError(err).StatusCode())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected.Limit, p.Limit)
+			assert.Equal(t, tt.expected.Page, p.Page)
+			assert.Equal(t, tt.expected.Offset, p.Offset)
+			assert.Equal(t, tt.expected.Cursor, p.Cursor)
+		})
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	type position struct {
+		ID        int64  `json:"id"`
+		CreatedAt string `json:"created_at"`
+	}
+	token, err := pageOpts.EncodeCursor(position{ID: 42, CreatedAt: "2021-11-02"})
+	assert.NoError(t, err)
+	assert.NotContains(t, token, "created_at")
+
+	r := httptest.NewRequest(http.MethodGet, "/orders?cursor="+token, nil)
+	p, err := ParsePage(r, pageOpts)
+	assert.NoError(t, err)
+	var actual position
+	assert.NoError(t, p.DecodeCursor(&actual))
+	assert.Equal(t, position{ID: 42, CreatedAt: "2021-11-02"}, actual)
+
+	forged, err := PaginationOpts{Secret: []byte("other")}.EncodeCursor(position{ID: 1})
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(pageOpts.DecodeCursor(forged, &actual), ErrInvalidCursor))
+
+	_, err = PaginationOpts{}.EncodeCursor(position{})
+	assert.Error(t, err)
+}
+
+func TestOffsetPagination(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/orders?status=paid&limit=10&page=2", nil)
+	p, err := ParsePage(r, pageOpts)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	pagination := p.OffsetPagination(w, r, 10, 35)
+	assert.NoError(t, ResponseJSONPayload(w, r, http.StatusOK, []ledgerEntry{{ID: 11}}, pagination))
+
+	assert.Equal(t, []string{
+		`</orders?limit=10&page=1&status=paid>; rel="first"`,
+		`</orders?limit=10&page=1&status=paid>; rel="prev"`,
+		`</orders?limit=10&page=3&status=paid>; rel="next"`,
+		`</orders?limit=10&page=4&status=paid>; rel="last"`,
+	}, w.Header().Values(HeaderLink))
+
+	var actual struct {
+		Pagination map[string]interface{} `json:"pagination"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, map[string]interface{}{
+		"limit":       float64(10),
+		"next_cursor": nil,
+		"page":        float64(2),
+		"offset":      float64(10),
+		"total":       float64(35),
+		"has_more":    true,
+	}, actual.Pagination)
+}
+
+func TestOffsetPaginationLastPage(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/orders?offset=30&limit=10", nil)
+	p, err := ParsePage(r, pageOpts)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	pagination := p.OffsetPagination(w, r, 4, -1)
+	assert.False(t, *pagination.HasMore)
+	assert.Nil(t, pagination.Total)
+	assert.Equal(t, []string{
+		`</orders?limit=10&offset=0>; rel="first"`,
+		`</orders?limit=10&offset=20>; rel="prev"`,
+	}, w.Header().Values(HeaderLink))
+}
+
+func TestCursorPagination(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/orders?limit=2", nil)
+	p, err := ParsePage(r, pageOpts)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	pagination, err := p.CursorPagination(w, r, map[string]int{"id": 3}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, ResponseJSONPayload(w, r, http.StatusOK, pagination))
+	assert.True(t, *pagination.HasMore)
+	assert.Nil(t, pagination.PrevCursor)
+
+	links := w.Header().Values(HeaderLink)
+	assert.Len(t, links, 1)
+	assert.True(t, strings.HasSuffix(links[0], `>; rel="next"`))
+
+	next := httptest.NewRequest(http.MethodGet, strings.TrimSuffix(strings.TrimPrefix(links[0], "<"), `>; rel="next"`), nil)
+	p, err = ParsePage(next, pageOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, pagination.NextCursor, p.Cursor)
+	var position map[string]int
+	assert.NoError(t, p.DecodeCursor(&position))
+	assert.Equal(t, 3, position["id"])
+	assert.Contains(t, w.Body.String(), `"has_more":true`)
+}
@@ -0,0 +1,112 @@
+package bifrost
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// Payload writes the response envelope straight from a typed value, kept
+// under an explicit data key, instead of the map round-trip of
+// ResponseJSONPayload. Fields keep their order, json.Marshaler
+// implementations and number precision.
+//
+//	return bifrost.NewPayload("orders", orders).
+//		WithPagination(bifrost.Pagination{Limit: 20}).
+//		Write(w, r, http.StatusOK)
+type Payload[T any] struct {
+	key        string
+	data       T
+	pagination interface{}
+	compat     bool
+}
+
+// NewPayload returns the payload holding data under key, or holding data
+// itself as the data member when key is empty.
+func NewPayload[T any](key string, data T) *Payload[T] {
+	return &Payload[T]{key: key, data: data}
+}
+
+// WithPagination sets the pagination member, {} when it is not set.
+func (p *Payload[T]) WithPagination(pagination interface{}) *Payload[T] {
+	p.pagination = pagination
+	return p
+}
+
+// Compat makes Write produce the exact bytes of ResponseJSONPayload for
+// existing clients, at the cost of its map round-trip. The data key is then
+// derived from the type of data as ResponseJSONPayload does.
+func (p *Payload[T]) Compat() *Payload[T] {
+	p.compat = true
+	return p
+}
+
+// Write encodes the envelope as json with the status code.
+func (p *Payload[T]) Write(w http.ResponseWriter, r *http.Request, code int) error {
+	if p.compat {
+		responses := []interface{}{p.data}
+		if p.pagination != nil {
+			responses = append(responses, p.pagination)
+		}
+		return ResponseJSONPayload(w, r, code, responses...)
+	}
+
+	w.Header().Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
+	pagination := p.pagination
+	if pagination == nil {
+		pagination = make(map[string]interface{})
+	}
+	env := payloadEnvelope[T]{
+		Version: Version{
+			Label:  "v1",
+			Number: "0.1.0",
+		},
+		Meta:       Meta{Code: http.StatusText(code), Warning: deprecationWarning(r), RequestID: GetRequestID(r.Context())},
+		Data:       payloadData[T]{key: p.key, value: p.data},
+		Pagination: pagination,
+	}
+	if ver, ok := r.Context().Value(CtxVersion).(Version); ok {
+		env.Version = ver
+	}
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(true)
+	if err := enc.Encode(env); err != nil {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+	w.WriteHeader(code)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type payloadEnvelope[T any] struct {
+	Version    Version        `json:"version"`
+	Meta       Meta           `json:"meta"`
+	Data       payloadData[T] `json:"data"`
+	Pagination interface{}    `json:"pagination"`
+}
+
+type payloadData[T any] struct {
+	key   string
+	value T
+}
+
+func (d payloadData[T]) MarshalJSON() ([]byte, error) {
+	value, err := json.Marshal(d.value)
+	if err != nil || d.key == "" {
+		return value, err
+	}
+	key, err := json.Marshal(d.key)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 0, len(key)+len(value)+3)
+	b = append(b, '{')
+	b = append(b, key...)
+	b = append(b, ':')
+	b = append(b, value...)
+	return append(b, '}'), nil
+}
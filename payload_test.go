@@ -0,0 +1,98 @@
+package bifrost
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ledgerEntry struct {
+	ID      int64  `json:"id"`
+	Account string `json:"account"`
+	Amount  int64  `json:"amount"`
+}
+
+func ledgerEntries(n int) []ledgerEntry {
+	entries := make([]ledgerEntry, 0, n)
+	for i := 0; i < n; i++ {
+		entries = append(entries, ledgerEntry{ID: 9007199254740993 + int64(i), Account: "kas <besar>", Amount: int64(i * 1000)})
+	}
+	return entries
+}
+
+func TestPayload(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	err = NewPayload("entries", ledgerEntries(2)).WithPagination(Pagination{Limit: 2, NextCursor: "abc"}).Write(w, r, http.StatusOK)
+	assert.NoError(t, err)
+	assert.Equal(t, MIMEApplicationJSONCharsetUTF8, w.Header().Get(HeaderContentType))
+	assert.Equal(t, `{"version":{"label":"v1","number":"0.1.0"},"meta":{"code":"OK"},"data":{"entries":[`+
+		`{"id":9007199254740993,"account":"kas \u003cbesar\u003e","amount":0},`+
+		`{"id":9007199254740994,"account":"kas \u003cbesar\u003e","amount":1000}]},`+
+		`"pagination":{"limit":2,"next_cursor":"abc"}}`+"\n", w.Body.String())
+}
+
+func TestPayloadWithoutKey(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	SemanticVersion(r, "v2", "2.0.0")
+	w := httptest.NewRecorder()
+
+	err = NewPayload("", ledgerEntry{ID: 1, Account: "kas"}).Write(w, r, http.StatusCreated)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"version":{"label":"v2","number":"2.0.0"},"meta":{"code":"Created"},"data":{"id":1,"account":"kas","amount":0},"pagination":{}}`+"\n", w.Body.String())
+}
+
+func TestPayloadCompat(t *testing.T) {
+	cases := []struct {
+		name string
+		data interface{}
+	}{
+		{"slice", ledgerEntries(3)},
+		{"struct", ledgerEntry{ID: 1, Account: "kas", Amount: 5}},
+		{"map", map[string]interface{}{"message": "transaksi telah sukses"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			assert.NoError(t, err)
+
+			legacy := httptest.NewRecorder()
+			assert.NoError(t, ResponseJSONPayload(legacy, r, http.StatusOK, tt.data, Pagination{Limit: 3}))
+			compat := httptest.NewRecorder()
+			assert.NoError(t, NewPayload("ignored", tt.data).WithPagination(Pagination{Limit: 3}).Compat().Write(compat, r, http.StatusOK))
+
+			assert.Equal(t, legacy.Body.Bytes(), compat.Body.Bytes())
+			assert.Equal(t, legacy.Header(), compat.Header())
+		})
+	}
+}
+
+func BenchmarkResponseJSONPayload(b *testing.B) {
+	entries := ledgerEntries(1000)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := ResponseJSONPayload(httptest.NewRecorder(), r, http.StatusOK, entries); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPayload(b *testing.B) {
+	entries := ledgerEntries(1000)
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := NewPayload("ledger_entry", entries).Write(httptest.NewRecorder(), r, http.StatusOK); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
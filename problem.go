@@ -0,0 +1,156 @@
+package bifrost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+var (
+	ErrorFormatCtxKey = &ctxRender{"error format"}
+)
+
+// ErrorFormat is how HandlerAdapter renders errors.
+type ErrorFormat int
+
+const (
+	// ErrorFormatNegotiate renders problem details when the client accepts
+	// application/problem+json, and the response envelope otherwise.
+	ErrorFormatNegotiate ErrorFormat = iota
+	// ErrorFormatEnvelope renders the Response{Version, Meta, Data, Pagination} envelope.
+	ErrorFormatEnvelope
+	// ErrorFormatProblem renders RFC 7807 problem details.
+	ErrorFormatProblem
+)
+
+// SetErrorFormat is a middleware that forces how errors are rendered.
+func SetErrorFormat(format ErrorFormat) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), ErrorFormatCtxKey, format))
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// GetErrorFormat returns the error format of the request, negotiated from
+// its Accept header unless a middleware has forced one.
+func GetErrorFormat(r *http.Request) ErrorFormat {
+	if format, ok := r.Context().Value(ErrorFormatCtxKey).(ErrorFormat); ok && format != ErrorFormatNegotiate {
+		return format
+	}
+	if acceptsProblem(r.Header.Get(HeaderAccept)) {
+		return ErrorFormatProblem
+	}
+	return ErrorFormatEnvelope
+}
+
+func acceptsProblem(accept string) bool {
+	for _, mr := range ParseAccept(accept) {
+		if mr.Type == MIMEApplicationProblemJSON && mr.Quality > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Problem is an RFC 7807 problem details object. Extensions are marshalled
+// as additional members next to the standard ones.
+type Problem struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	members["type"] = p.Type
+	if p.Title != "" {
+		members["title"] = p.Title
+	}
+	if p.Status != 0 {
+		members["status"] = p.Status
+	}
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// ProblemResponse builds the problem details for e. The error code, field
+// details, trace id and request id of the request are added as extension
+// members.
+func ProblemResponse(w http.ResponseWriter, r *http.Request, e *Error) *Problem {
+	code := e.StatusCode()
+	p := &Problem{
+		Type:       e.Type,
+		Title:      http.StatusText(code),
+		Status:     code,
+		Detail:     e.message(),
+		Instance:   r.URL.RequestURI(),
+		Extensions: make(map[string]interface{}),
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if e.Code != "" {
+		p.Extensions["code"] = e.Code
+	}
+	if len(e.Details) > 0 {
+		p.Extensions["errors"] = e.Details
+	}
+	if traceID := errorTraceID(w, r); traceID != "" {
+		p.Extensions["trace_id"] = traceID
+	}
+	if requestID := GetRequestID(r.Context()); requestID != "" {
+		p.Extensions["request_id"] = requestID
+	}
+	return p
+}
+
+func errorTraceID(w http.ResponseWriter, r *http.Request) string {
+	if traceID, ok := r.Context().Value(TracerContext).(string); ok {
+		return traceID
+	}
+	return w.Header().Get(HeaderXTraceId)
+}
+
+// setErrorHeaders prepares the headers of an error response, they have to
+// be set before the status is written.
+func setErrorHeaders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if GetErrorFormat(r) == ErrorFormatProblem {
+		w.Header().Set(HeaderContentType, MIMEApplicationProblemJSON)
+		return
+	}
+	if w.Header().Get(HeaderContentType) == "" {
+		w.Header().Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
+	}
+}
+
+// WriteError renders e in the error format of the request.
+func WriteError(w http.ResponseWriter, r *http.Request, e *Error) error {
+	var body interface{} = ErrorResponse(r, e)
+	if GetErrorFormat(r) == ErrorFormatProblem {
+		body = ProblemResponse(w, r, e)
+	}
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+	setErrorHeaders(w, r)
+	w.WriteHeader(e.StatusCode())
+	_, err = w.Write(bytes)
+	return err
+}
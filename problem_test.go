@@ -0,0 +1,82 @@
+package bifrost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveProblem(t *testing.T, r *http.Request, handler http.Handler) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	body := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w, body
+}
+
+func TestProblemNegotiated(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/orders/42?expand=items", nil)
+	assert.NoError(t, err)
+	r.Header.Set(HeaderAccept, "application/json;q=0.5, application/problem+json")
+	r = r.WithContext(context.WithValue(r.Context(), TracerContext, "4bf92f3577b34da6a3ce929d0e0e4736"))
+
+	w, body := serveProblem(t, r, HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		return errOrderNotFound.WithDetails(ErrorDetail{Field: "order_id", Message: "unknown id"})
+	}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, w.Header().Get(HeaderContentType))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "about:blank",
+		"title":    http.StatusText(http.StatusNotFound),
+		"status":   float64(http.StatusNotFound),
+		"detail":   "order does not exist",
+		"instance": "/orders/42?expand=items",
+		"code":     "order_not_found",
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"errors": []interface{}{
+			map[string]interface{}{"field": "order_id", "message": "unknown id"},
+		},
+	}, body)
+}
+
+func TestProblemRefusedByQuality(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	r.Header.Set(HeaderAccept, "application/problem+json;q=0")
+	assert.Equal(t, ErrorFormatEnvelope, GetErrorFormat(r))
+}
+
+func TestProblemForcedByRouter(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+
+	handler := SetErrorFormat(ErrorFormatProblem)(HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set(HeaderXTraceId, "trace-bifrost-id")
+		return ErrUnauthorized(w, r, errors.New("token expired"))
+	}))
+	w, body := serveProblem(t, r, handler)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, w.Header().Get(HeaderContentType))
+	assert.Equal(t, "token expired", body["detail"])
+	assert.Equal(t, "trace-bifrost-id", body["trace_id"])
+}
+
+func TestProblemEnvelopeForcedByRouter(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	assert.NoError(t, err)
+	r.Header.Set(HeaderAccept, MIMEApplicationProblemJSON)
+
+	handler := SetErrorFormat(ErrorFormatEnvelope)(HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		return errOrderNotFound
+	}))
+	w, body := serveProblem(t, r, handler)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, body, "meta")
+}
@@ -0,0 +1,94 @@
+package bifrost
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrPanic is answered for a request whose handler panicked.
+var ErrPanic = NewError(http.StatusInternalServerError, "internal_error", "")
+
+// Recoverer is a middleware answering a panic of the next handlers with the
+// 500 error, rendered as HandlerAdapter renders errors. The panic is logged
+// with its stack and trace id. When the response has already started, or
+// the panic is http.ErrAbortHandler, http.ErrAbortHandler is panicked for
+// net/http to abort the response rather than end it as a success. Used
+// after HttpTracer, the span records the 500.
+func Recoverer(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+			GetLogger(r.Context()).Error().
+				Str("trace_id", errorTraceID(w, r)).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str("panic", fmt.Sprint(rvr)).
+				Str("stack", string(debug.Stack())).
+				Msg("Recovered from panic")
+			if ww.Status() != 0 || ww.BytesWritten() > 0 {
+				panic(http.ErrAbortHandler)
+			}
+			WriteError(ww, r, ErrPanic.Wrap(fmt.Errorf("panic: %v", rvr)))
+		}()
+		next.ServeHTTP(ww, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// RecoveryServerOptions returns the options chaining the unary and stream
+// interceptors which answer a panic of an rpc with codes.Internal.
+func RecoveryServerOptions() []rpc.ServerOption {
+	return []rpc.ServerOption{
+		rpc.ChainUnaryInterceptor(recoverUnary),
+		rpc.ChainStreamInterceptor(recoverStream),
+	}
+}
+
+func recoverUnary(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = recoveredRPC(ctx, info.FullMethod, rvr)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv interface{}, ss rpc.ServerStream, info *rpc.StreamServerInfo, handler rpc.StreamHandler) (err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = recoveredRPC(ss.Context(), info.FullMethod, rvr)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func recoveredRPC(ctx context.Context, fullMethod string, rvr interface{}) error {
+	traceID, ok := ctx.Value(TracerContext).(string)
+	if !ok {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			traceID = sc.TraceID().String()
+		}
+	}
+	GetLogger(ctx).Error().
+		Str("trace_id", traceID).
+		Str("method", fullMethod).
+		Str("panic", fmt.Sprint(rvr)).
+		Str("stack", string(debug.Stack())).
+		Msg("Recovered from panic")
+	return status.Error(codes.Internal, http.StatusText(http.StatusInternalServerError))
+}
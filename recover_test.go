@@ -0,0 +1,156 @@
+package bifrost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	rpc "google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { log.Logger = logger })
+	return &buf
+}
+
+func TestRecoverer(t *testing.T) {
+	buf := captureLog(t)
+	spans := tracetest.NewInMemoryExporter()
+	handler := NewHttpTracer(TracerOpts{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))})(
+		Recoverer(HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+			var orders map[string]int
+			orders["boom"]++
+			return nil
+		})))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, MIMEApplicationJSONCharsetUTF8, w.Header().Get(HeaderContentType))
+	var actual struct {
+		Meta Meta `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, Meta{Code: "500", Type: "Internal Server Error", Message: "Internal Server Error", ErrorCode: "internal_error"}, actual.Meta)
+
+	ended := spans.GetSpans()
+	assert.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status.Code)
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "Recovered from panic", entry["message"])
+	assert.Equal(t, ended[0].SpanContext.TraceID().String(), entry["trace_id"])
+	assert.Equal(t, "assignment to entry in nil map", entry["panic"])
+	assert.Contains(t, entry["stack"], "recover_test.go")
+}
+
+func TestRecovererProblem(t *testing.T) {
+	captureLog(t)
+	handler := SetErrorFormat(ErrorFormatProblem)(Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, w.Header().Get(HeaderContentType))
+	assert.NotContains(t, w.Body.String(), "boom")
+}
+
+func TestRecovererStartedResponse(t *testing.T) {
+	buf := captureLog(t)
+	handler := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"partial":`))
+		panic(fmt.Errorf("encoder failed"))
+	}))
+	w := httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	})
+	assert.Equal(t, `{"partial":`, w.Body.String())
+	assert.Contains(t, buf.String(), `"panic":"encoder failed"`)
+
+	// net/http aborts the connection, the client sees no complete response
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	if err == nil {
+		_, err = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	assert.Error(t, err)
+}
+
+func TestRecovererAbortHandler(t *testing.T) {
+	buf := captureLog(t)
+	handler := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+	})
+	assert.Empty(t, buf.String())
+}
+
+type panickingHealth struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (panickingHealth) Check(context.Context, *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	panic("check failed")
+}
+
+func (panickingHealth) Watch(*healthpb.HealthCheckRequest, healthpb.Health_WatchServer) error {
+	panic("watch failed")
+}
+
+func TestGRPCRecovery(t *testing.T) {
+	buf := captureLog(t)
+	port, err := findOpenPort()
+	assert.NoError(t, err)
+	srv := NewServerGRPC(GRPCOpts{Port: GRPCPort(port), Recovery: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = srv.RunContext(ctx, func(s *rpc.Server) {
+			healthpb.RegisterHealthServer(s, panickingHealth{})
+		})
+	}()
+	waitForPort(t, port)
+
+	conn, err := rpc.Dial(fmt.Sprintf("localhost:%d", port), rpc.WithInsecure())
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+
+	_, err = client.Check(callCtx, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, grpccodes.Internal, status.Code(err))
+	stream, err := client.Watch(callCtx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, grpccodes.Internal, status.Code(err))
+
+	assert.Contains(t, buf.String(), `"method":"/grpc.health.v1.Health/Check","panic":"check failed"`)
+	assert.Contains(t, buf.String(), `"method":"/grpc.health.v1.Health/Watch","panic":"watch failed"`)
+}
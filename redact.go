@@ -0,0 +1,82 @@
+package bifrost
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of redacted fields.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactedFields are the fields redacted when no BodyRedactor is set.
+var DefaultRedactedFields = []string{"password", "token", "access_token", "refresh_token", "secret", "authorization"}
+
+// BodyRedactor returns body with its sensitive values replaced.
+type BodyRedactor func(contentType string, body []byte) []byte
+
+// RedactFields redacts the json members and form values named by fields, in
+// any case. Json bodies, +json media types included, are redacted at any
+// depth. A body of another type, or failing to parse, is replaced by
+// Redacted as a whole rather than kept.
+func RedactFields(fields ...string) BodyRedactor {
+	redacted := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		redacted[strings.ToLower(f)] = true
+	}
+	return func(contentType string, body []byte) []byte {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return []byte(Redacted)
+		}
+		switch {
+		case mediaType == MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.UseNumber()
+			var v interface{}
+			if err := dec.Decode(&v); err != nil || dec.More() {
+				return []byte(Redacted)
+			}
+			b, err := json.Marshal(redactJSON(v, redacted))
+			if err != nil {
+				return []byte(Redacted)
+			}
+			return b
+		case mediaType == MIMEApplicationForm:
+			values, err := url.ParseQuery(string(body))
+			if err != nil {
+				return []byte(Redacted)
+			}
+			for k, v := range values {
+				if redacted[strings.ToLower(k)] {
+					for n := range v {
+						v[n] = Redacted
+					}
+				}
+			}
+			return []byte(values.Encode())
+		default:
+			return []byte(Redacted)
+		}
+	}
+}
+
+func redactJSON(v interface{}, redacted map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, member := range v {
+			if redacted[strings.ToLower(k)] {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = redactJSON(member, redacted)
+		}
+	case []interface{}:
+		for n, item := range v {
+			v[n] = redactJSON(item, redacted)
+		}
+	}
+	return v
+}
@@ -0,0 +1,36 @@
+package bifrost
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactFields(t *testing.T) {
+	redact := RedactFields("password", "Token")
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"json", MIMEApplicationJSONCharsetUTF8,
+			`{"user":"gopher","PASSWORD":"hunter2","devices":[{"token":"abc","id":12345678901234567890}]}`,
+			`{"PASSWORD":"[REDACTED]","devices":[{"id":12345678901234567890,"token":"[REDACTED]"}],"user":"gopher"}`},
+		{"json array", MIMEApplicationJSON, `[{"password":1},2]`, `[{"password":"[REDACTED]"},2]`},
+		{"json suffix", "application/vnd.api+json", `{"data":{"password":"hunter2"}}`, `{"data":{"password":"[REDACTED]"}}`},
+		{"malformed json", MIMEApplicationJSON, `{"password":"hunter2"`, Redacted},
+		{"trailing json", MIMEApplicationJSON, `{"id":1} {"password":"hunter2"}`, Redacted},
+		{"form", MIMEApplicationForm, `user=gopher&token=abc&token=def`, `token=%5BREDACTED%5D&token=%5BREDACTED%5D&user=gopher`},
+		{"malformed form", MIMEApplicationForm, `password=hunter2&user=%zz`, Redacted},
+		{"text", MIMETextPlain, `password=hunter2`, Redacted},
+		{"json prefix", "application/jsonp", `{"password":"hunter2"}`, Redacted},
+		{"malformed content type", "application/json;;", `{"password":"hunter2"}`, Redacted},
+		{"no content type", "", `{"password":"hunter2"}`, Redacted},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, string(redact(tt.contentType, []byte(tt.body))))
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package bifrost
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var RequestIDCtxKey = &ctxRender{"request_id"}
+
+// metadataRequestID is the grpc metadata key of the request id.
+const metadataRequestID = "x-request-id"
+
+// maxRequestIDLength bounds the incoming request ids accepted.
+const maxRequestIDLength = 128
+
+// RequestIDOpts configures the middleware returned by NewRequestID.
+type RequestIDOpts struct {
+	// Header carries the request id, X-Request-Id when empty.
+	Header string
+	// Trusted tells whether the id sent by the client of r is kept, any
+	// is when nil. Untrusted and malformed ids are replaced.
+	Trusted func(r *http.Request) bool
+	// Generator creates the ids, GenerateRequestID when nil.
+	Generator func() string
+}
+
+func (o RequestIDOpts) header() string {
+	if o.Header == "" {
+		return HeaderXRequestID
+	}
+	return o.Header
+}
+
+func (o RequestIDOpts) generate() string {
+	if o.Generator == nil {
+		return GenerateRequestID()
+	}
+	return o.Generator()
+}
+
+// RequestID is the middleware of NewRequestID with the default options.
+func RequestID(next http.Handler) http.Handler {
+	return NewRequestID(RequestIDOpts{})(next)
+}
+
+// NewRequestID returns a middleware giving each request an id, the one sent
+// by a trusted client or a generated one. The id is stored in the context,
+// echoed in the response header and the Meta of the envelopes, and forwarded
+// by the Transport client and the RequestIDDialOptions.
+func NewRequestID(opts RequestIDOpts) func(next http.Handler) http.Handler {
+	header := opts.header()
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !validRequestID(id) || (opts.Trusted != nil && !opts.Trusted(r)) {
+				id = opts.generate()
+			}
+			w.Header().Set(header, id)
+			next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// GetRequestID returns the request id stored in ctx, empty when none.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDCtxKey).(string)
+	return id
+}
+
+// WithRequestID returns a copy of ctx holding the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestIDCtxKey, id)
+}
+
+// requestID is the id of r, the one of its header when no middleware stored one.
+func requestID(r *http.Request) string {
+	if id := GetRequestID(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(HeaderXRequestID)
+}
+
+// validRequestID accepts the non empty ids of printable ascii, spaces aside.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var lastRequestID struct {
+	sync.Mutex
+	ms      uint64
+	entropy [10]byte
+}
+
+// GenerateRequestID returns a ULID, 26 characters sorting in the order they
+// were generated: a millisecond timestamp followed by 80 random bits, which
+// are incremented for the ids of the same millisecond.
+func GenerateRequestID() string {
+	var id [16]byte
+	lastRequestID.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms > lastRequestID.ms {
+		lastRequestID.ms = ms
+		_, _ = rand.Read(lastRequestID.entropy[:])
+	} else {
+		// same millisecond or a clock gone backwards
+		for i := len(lastRequestID.entropy) - 1; i >= 0; i-- {
+			lastRequestID.entropy[i]++
+			if lastRequestID.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+	for i := 0; i < 6; i++ {
+		id[i] = byte(lastRequestID.ms >> (40 - 8*i))
+	}
+	copy(id[6:], lastRequestID.entropy[:])
+	lastRequestID.Unlock()
+
+	// 128 bits make 26 characters of 5 bits, the first one holding 3
+	var out [26]byte
+	for i := range out {
+		v := 0
+		for b := 0; b < 5; b++ {
+			v <<= 1
+			if pos := i*5 + b - 2; pos >= 0 && id[pos/8]&(0x80>>(pos%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockfordAlphabet[v]
+	}
+	return string(out[:])
+}
+
+// requestIDTransport sets the request id of the context in the header of
+// the outbound requests which carry none.
+type requestIDTransport struct {
+	base   http.RoundTripper
+	header string
+}
+
+func (t requestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if id := GetRequestID(r.Context()); id != "" && r.Header.Get(t.header) == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set(t.header, id)
+	}
+	return t.base.RoundTrip(r)
+}
+
+// RequestIDDialOptions returns the options chaining the client interceptors
+// which send the request id of the context in the metadata of the rpcs.
+func RequestIDDialOptions() []rpc.DialOption {
+	return []rpc.DialOption{
+		rpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *rpc.ClientConn, invoker rpc.UnaryInvoker, opts ...rpc.CallOption) error {
+			return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+		}),
+		rpc.WithChainStreamInterceptor(func(ctx context.Context, desc *rpc.StreamDesc, cc *rpc.ClientConn, method string, streamer rpc.Streamer, opts ...rpc.CallOption) (rpc.ClientStream, error) {
+			return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+		}),
+	}
+}
+
+func outgoingRequestID(ctx context.Context) context.Context {
+	id := GetRequestID(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(metadataRequestID)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, metadataRequestID, id)
+}
+
+// requestIDServerOptions returns the interceptors storing the request id of
+// the incoming metadata, or a generated one, in the context of the rpcs and
+// sending it back in the header metadata. The incoming id is kept when
+// trusted tells so, or is nil, and it is valid as for NewRequestID.
+func requestIDServerOptions(trusted func(ctx context.Context) bool) []rpc.ServerOption {
+	return []rpc.ServerOption{
+		rpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+			ctx = incomingRequestID(ctx, trusted)
+			_ = rpc.SetHeader(ctx, metadata.Pairs(metadataRequestID, GetRequestID(ctx)))
+			return handler(ctx, req)
+		}),
+		rpc.ChainStreamInterceptor(func(srv interface{}, ss rpc.ServerStream, _ *rpc.StreamServerInfo, handler rpc.StreamHandler) error {
+			ctx := incomingRequestID(ss.Context(), trusted)
+			_ = ss.SetHeader(metadata.Pairs(metadataRequestID, GetRequestID(ctx)))
+			return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+func incomingRequestID(ctx context.Context, trusted func(ctx context.Context) bool) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(metadataRequestID)
+	if len(values) > 0 && validRequestID(values[0]) && (trusted == nil || trusted(ctx)) {
+		return WithRequestID(ctx, values[0])
+	}
+	return WithRequestID(ctx, GenerateRequestID())
+}
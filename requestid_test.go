@@ -0,0 +1,218 @@
+package bifrost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGenerateRequestID(t *testing.T) {
+	ids := make([]string, 1000)
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		ids[i] = GenerateRequestID()
+		assert.Len(t, ids[i], 26)
+		assert.Equal(t, "", strings.Trim(ids[i], crockfordAlphabet))
+		seen[ids[i]] = true
+	}
+	assert.Len(t, seen, len(ids))
+	assert.True(t, sort.StringsAreSorted(ids))
+	assert.True(t, validRequestID(ids[0]))
+}
+
+func TestRequestID(t *testing.T) {
+	var logged bytes.Buffer
+	handler := NewRequestID(RequestIDOpts{
+		Trusted: func(r *http.Request) bool { return !strings.HasPrefix(r.RemoteAddr, "203.0.113.") },
+	})(RequestLogger(HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		GetLogger(r.Context()).Info().Msg("handled")
+		if r.URL.Query().Get("fail") != "" {
+			return errOrderNotFound
+		}
+		return ResponseJSONPayload(w, r, http.StatusOK, map[string]interface{}{"id": 1})
+	})))
+	call := func(target string, id string, remote string) (*httptest.ResponseRecorder, Meta) {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r = r.WithContext(WithLogger(r.Context(), zerolog.New(&logged)))
+		if id != "" {
+			r.Header.Set(HeaderXRequestID, id)
+		}
+		if remote != "" {
+			r.RemoteAddr = remote
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		var body struct {
+			Meta Meta `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body.Meta
+	}
+
+	w, meta := call("/orders", "req-1", "")
+	assert.Equal(t, "req-1", w.Header().Get(HeaderXRequestID))
+	assert.Equal(t, "req-1", meta.RequestID)
+	assert.Contains(t, logged.String(), `"request_id":"req-1"`)
+
+	w, meta = call("/orders?fail=1", "req-2", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "req-2", meta.RequestID)
+
+	w, meta = call("/orders", "", "")
+	generated := w.Header().Get(HeaderXRequestID)
+	assert.Len(t, generated, 26)
+	assert.Equal(t, generated, meta.RequestID)
+
+	w, _ = call("/orders", "req-3", "203.0.113.9:41234")
+	assert.Len(t, w.Header().Get(HeaderXRequestID), 26)
+
+	w, _ = call("/orders", "bad id", "")
+	assert.Len(t, w.Header().Get(HeaderXRequestID), 26)
+	w, _ = call("/orders", strings.Repeat("a", maxRequestIDLength+1), "")
+	assert.Len(t, w.Header().Get(HeaderXRequestID), 26)
+}
+
+func TestRequestIDProblem(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/orders/42", nil)
+	assert.NoError(t, err)
+	r.Header.Set(HeaderAccept, MIMEApplicationProblemJSON)
+	r.Header.Set(HeaderXRequestID, "req-1")
+
+	_, body := serveProblem(t, r, RequestID(HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		return errOrderNotFound
+	})))
+	assert.Equal(t, "req-1", body["request_id"])
+}
+
+func TestTransportRequestID(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get(HeaderXRequestID)))
+	}))
+	defer upstream.Close()
+	client := Transport(false, 5)
+
+	call := func(ctx context.Context, header string) string {
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+		assert.NoError(t, err)
+		if header != "" {
+			r.Header.Set(HeaderXRequestID, header)
+		}
+		res, err := client.Do(r)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(res.Body)
+		assert.Equal(t, header, r.Header.Get(HeaderXRequestID))
+		return buf.String()
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	assert.Equal(t, "req-1", call(ctx, ""))
+	assert.Equal(t, "req-2", call(ctx, "req-2"))
+	assert.Equal(t, "", call(context.Background(), ""))
+}
+
+func TestClientRequestIDHeader(t *testing.T) {
+	received := make(chan http.Header, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	for name, opts := range map[string]ClientOpts{
+		"plain":     {RequestIDHeader: "X-Correlation-Id"},
+		"telemetry": {RequestIDHeader: "X-Correlation-Id", Telemetry: &ClientTelemetryOpts{Level: zerolog.Disabled}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(opts)
+			assert.NoError(t, err)
+			r, err := http.NewRequestWithContext(WithRequestID(context.Background(), "req-1"), http.MethodGet, upstream.URL, nil)
+			assert.NoError(t, err)
+			res, err := client.Do(r)
+			assert.NoError(t, err)
+			res.Body.Close()
+			header := <-received
+			assert.Equal(t, "req-1", header.Get("X-Correlation-Id"))
+			assert.Empty(t, header.Get(HeaderXRequestID))
+		})
+	}
+}
+
+func TestIncomingRequestID(t *testing.T) {
+	incoming := func(id string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(metadataRequestID, id))
+	}
+	trusted := func(ctx context.Context) bool { return false }
+
+	assert.Equal(t, "req-1", GetRequestID(incomingRequestID(incoming("req-1"), nil)))
+	assert.Equal(t, "req-1", GetRequestID(incomingRequestID(incoming("req-1"), func(ctx context.Context) bool { return true })))
+	for name, ctx := range map[string]context.Context{
+		"untrusted": incomingRequestID(incoming("req-1"), trusted),
+		"too long":  incomingRequestID(incoming(strings.Repeat("a", maxRequestIDLength+1)), nil),
+		"malformed": incomingRequestID(incoming("req 1"), nil),
+		"missing":   incomingRequestID(context.Background(), nil),
+	} {
+		id := GetRequestID(ctx)
+		assert.Len(t, id, 26, name)
+		assert.NotEqual(t, "req-1", id, name)
+	}
+}
+
+func TestGRPCRequestID(t *testing.T) {
+	var logged bytes.Buffer
+	logger := zerolog.New(&logged)
+	port, err := findOpenPort()
+	assert.NoError(t, err)
+	srv := NewServerGRPC(GRPCOpts{Port: GRPCPort(port), Telemetry: &GRPCTelemetryOpts{}, Logger: &logger})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.RunContext(ctx, func(s *rpc.Server) {
+			healthpb.RegisterHealthServer(s, health.NewServer())
+		})
+	}()
+	waitForPort(t, port)
+
+	conn, err := rpc.Dial(fmt.Sprintf("localhost:%d", port), append(RequestIDDialOptions(), rpc.WithInsecure())...)
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+
+	var header metadata.MD
+	_, err = client.Check(WithRequestID(callCtx, "req-1"), &healthpb.HealthCheckRequest{}, rpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"req-1"}, header.Get(metadataRequestID))
+
+	_, err = client.Check(callCtx, &healthpb.HealthCheckRequest{}, rpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Len(t, header.Get(metadataRequestID), 1)
+	assert.Len(t, header.Get(metadataRequestID)[0], 26)
+
+	watchCtx, watchCancel := context.WithCancel(WithRequestID(callCtx, "req-2"))
+	stream, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	header, err = stream.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"req-2"}, header.Get(metadataRequestID))
+	watchCancel()
+	cancel()
+	<-done
+
+	assert.Contains(t, logged.String(), `"request_id":"req-1","path":"/grpc.health.v1.Health/Check"`)
+}
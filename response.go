@@ -27,14 +27,18 @@ var (
 )
 
 type Meta struct {
-	Code    string `json:"code,omitempty"`
-	Type    string `json:"error_type,omitempty"`
-	Message string `json:"error_message,omitempty"`
+	Code      string        `json:"code,omitempty" xml:"code,omitempty"`
+	Type      string        `json:"error_type,omitempty" xml:"error_type,omitempty"`
+	Message   string        `json:"error_message,omitempty" xml:"error_message,omitempty"`
+	ErrorCode string        `json:"error_code,omitempty" xml:"error_code,omitempty"`
+	Details   []ErrorDetail `json:"error_details,omitempty" xml:"error_details,omitempty"`
+	Warning   string        `json:"warning,omitempty" xml:"warning,omitempty"`
+	RequestID string        `json:"request_id,omitempty" xml:"request_id,omitempty"`
 }
 
 type Version struct {
-	Label  string `json:"label,omitempty"`
-	Number string `json:"number,omitempty"`
+	Label  string `json:"label,omitempty" xml:"label,omitempty"`
+	Number string `json:"number,omitempty" xml:"number,omitempty"`
 }
 
 type Response struct {
@@ -52,13 +56,19 @@ func SemanticVersion(r *http.Request, label string, version string) {
 }
 
 type Pagination struct {
-	Limit      int         `json:"limit"`
-	NextCursor interface{} `json:"next_cursor"`
+	Limit      int         `json:"limit" xml:"limit"`
+	NextCursor interface{} `json:"next_cursor" xml:"next_cursor"`
+	PrevCursor interface{} `json:"prev_cursor,omitempty" xml:"prev_cursor,omitempty"`
+	Page       int         `json:"page,omitempty" xml:"page,omitempty"`
+	Offset     int         `json:"offset,omitempty" xml:"offset,omitempty"`
+	Total      *int64      `json:"total,omitempty" xml:"total,omitempty"`
+	HasMore    *bool       `json:"has_more,omitempty" xml:"has_more,omitempty"`
 }
 
+// GetLimit returns the limit, or the default limit of DefaultPagination.
 func (p Pagination) GetLimit() int {
 	if p.Limit < 1 {
-		return 10
+		return DefaultPagination.limit()
 	}
 	return p.Limit
 }
@@ -0,0 +1,239 @@
+package bifrost
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryOpts configures the round-tripper returned by NewRetryTransport.
+type RetryOpts struct {
+	// MaxAttempts bounds the attempts of a request, first one included, 3
+	// by default.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry, doubled for each of
+	// the next ones, 100 milliseconds by default.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, 5 seconds by default. A Retry-After asking
+	// for a longer wait ends the retries.
+	MaxDelay time.Duration
+	// AllowNonIdempotent retries every method. By default only the
+	// idempotent ones are, and the requests with an Idempotency-Key header.
+	AllowNonIdempotent bool
+	// RetryOn tells whether an attempt is retried, by default on transport
+	// errors and the 429, 502, 503 and 504 statuses.
+	RetryOn func(res *http.Response, err error) bool
+	// Budget, shared by the clients using it, caps their retries.
+	Budget *RetryBudget
+	// OnRetry is called before waiting for the retry of an attempt.
+	OnRetry func(r *http.Request, attempt int, wait time.Duration, res *http.Response, err error)
+}
+
+func (o RetryOpts) withDefaults() RetryOpts {
+	if o.MaxAttempts < 1 {
+		o.MaxAttempts = 3
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = 100 * time.Millisecond
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 5 * time.Second
+	}
+	if o.RetryOn == nil {
+		o.RetryOn = DefaultRetryOn
+	}
+	return o
+}
+
+// DefaultRetryOn retries the transport errors, cancellations and open
+// circuits aside, and the 429, 502, 503 and 504 statuses.
+func DefaultRetryOn(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrCircuitOpen)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff is the wait before the retry following attempt, drawn at random
+// up to the exponential delay (full jitter).
+func (o RetryOpts) backoff(attempt int) time.Duration {
+	delay := o.MaxDelay
+	if attempt < 32 {
+		if d := o.BaseDelay << uint(attempt-1); d > 0 && d < delay {
+			delay = d
+		}
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func (o RetryOpts) retryable(r *http.Request) bool {
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+	if o.AllowNonIdempotent {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get(HeaderIdempotencyKey) != "" || r.Header.Get(HeaderXIdempotencyKey) != ""
+}
+
+type retryTransport struct {
+	base http.RoundTripper
+	opts RetryOpts
+}
+
+// NewRetryTransport returns a round-tripper retrying the failed requests of
+// base with an exponential backoff, or after the delay of the Retry-After
+// header when the response has one. The wait ends with the context of the
+// request. Requests whose body cannot be replayed, GetBody being nil, are
+// not retried.
+func NewRetryTransport(base http.RoundTripper, opts RetryOpts) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, opts: opts.withDefaults()}
+}
+
+func (t *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.opts.Budget != nil {
+		t.opts.Budget.request()
+	}
+	retryable := t.opts.retryable(r)
+	req := r
+	for attempt := 1; ; attempt++ {
+		res, err := t.base.RoundTrip(req)
+		if !retryable || attempt >= t.opts.MaxAttempts || !t.opts.RetryOn(res, err) {
+			return res, err
+		}
+		wait := t.opts.backoff(attempt)
+		if res != nil {
+			if after, ok := retryAfter(res.Header.Get(HeaderRetryAfter)); ok {
+				if after > t.opts.MaxDelay {
+					return res, err
+				}
+				wait = after
+			}
+		}
+		if t.opts.Budget != nil && !t.opts.Budget.retry() {
+			return res, err
+		}
+		next, bodyErr := retryRequest(r)
+		if bodyErr != nil {
+			return res, err
+		}
+		req = next
+		if t.opts.OnRetry != nil {
+			t.opts.OnRetry(r, attempt, wait, res, err)
+		}
+		if res != nil {
+			// drain a little for the connection to be reused
+			_, _ = io.CopyN(io.Discard, res.Body, 4096)
+			res.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryRequest copies r with a fresh body.
+func retryRequest(r *http.Request) (*http.Request, error) {
+	req := r.Clone(r.Context())
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	return req, nil
+}
+
+// retryAfter parses a Retry-After value, in seconds or a HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// RetryBudget caps the retries to a ratio of the requests within a window,
+// keeping retries from piling onto a struggling server. The zero value
+// allows retries for 20% of the requests, 10 of them at least, per window of
+// 10 seconds.
+type RetryBudget struct {
+	// Ratio is the share of the requests which may be retried.
+	Ratio float64
+	// Min is the number of retries allowed within a window whatever the
+	// number of requests.
+	Min int
+	// Window is the period the requests and retries are counted over.
+	Window time.Duration
+
+	mu       sync.Mutex
+	start    time.Time
+	requests int
+	retries  int
+}
+
+// roll starts a new window when the current one is over.
+func (b *RetryBudget) roll() {
+	window := b.Window
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	if now := time.Now(); now.Sub(b.start) >= window {
+		b.start, b.requests, b.retries = now, 0, 0
+	}
+}
+
+func (b *RetryBudget) request() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	b.requests++
+}
+
+// retry withdraws a retry from the budget, false when it is exhausted.
+func (b *RetryBudget) retry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll()
+	ratio, min := b.Ratio, b.Min
+	if ratio <= 0 {
+		ratio = 0.2
+	}
+	if min <= 0 {
+		min = 10
+	}
+	if b.retries >= min && float64(b.retries+1) > ratio*float64(b.requests) {
+		return false
+	}
+	b.retries++
+	return true
+}
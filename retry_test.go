@@ -0,0 +1,213 @@
+package bifrost
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyServer answers statuses in turn, then 200, counting the requests.
+func flakyServer(t *testing.T, hits *int32, header http.Header, statuses ...int) *httptest.Server {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(hits, 1))
+		body, _ := io.ReadAll(r.Body)
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestRetryTransport(t *testing.T) {
+	var hits int32
+	upstream := flakyServer(t, &hits, nil, http.StatusServiceUnavailable, http.StatusBadGateway)
+	attempts := make([]int, 0)
+	client, err := NewClient(ClientOpts{Retry: &RetryOpts{
+		BaseDelay: time.Millisecond,
+		OnRetry: func(r *http.Request, attempt int, wait time.Duration, res *http.Response, err error) {
+			assert.LessOrEqual(t, wait, time.Duration(attempt)*time.Millisecond)
+			attempts = append(attempts, attempt)
+		},
+	}})
+	assert.NoError(t, err)
+
+	res, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(3), hits)
+	assert.Equal(t, []int{1, 2}, attempts)
+
+	atomic.StoreInt32(&hits, 0)
+	upstream = flakyServer(t, &hits, nil, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	res, err = client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, int32(3), hits)
+}
+
+func TestRetryTransportMethods(t *testing.T) {
+	post := func(client *http.Client, url string, key string) (*http.Response, string) {
+		r, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"id":1}`))
+		assert.NoError(t, err)
+		if key != "" {
+			r.Header.Set(HeaderIdempotencyKey, key)
+		}
+		res, err := client.Do(r)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+	client, err := NewClient(ClientOpts{Retry: &RetryOpts{BaseDelay: time.Millisecond}})
+	assert.NoError(t, err)
+
+	var hits int32
+	res, _ := post(client, flakyServer(t, &hits, nil, http.StatusServiceUnavailable).URL, "")
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, int32(1), hits)
+
+	hits = 0
+	res, body := post(client, flakyServer(t, &hits, nil, http.StatusServiceUnavailable).URL, "order-1")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, `{"id":1}`, body)
+	assert.Equal(t, int32(2), hits)
+
+	client, err = NewClient(ClientOpts{Retry: &RetryOpts{BaseDelay: time.Millisecond, AllowNonIdempotent: true}})
+	assert.NoError(t, err)
+	hits = 0
+	res, body = post(client, flakyServer(t, &hits, nil, http.StatusServiceUnavailable).URL, "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, `{"id":1}`, body)
+	assert.Equal(t, int32(2), hits)
+}
+
+func TestRetryTransportRetryAfter(t *testing.T) {
+	client, err := NewClient(ClientOpts{Retry: &RetryOpts{BaseDelay: time.Hour, MaxDelay: 2 * time.Second}})
+	assert.NoError(t, err)
+
+	var hits int32
+	upstream := flakyServer(t, &hits, http.Header{HeaderRetryAfter: {"0"}}, http.StatusTooManyRequests)
+	res, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(2), hits)
+
+	hits = 0
+	upstream = flakyServer(t, &hits, http.Header{HeaderRetryAfter: {"120"}}, http.StatusTooManyRequests)
+	res, err = client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, int32(1), hits)
+
+	hits = 0
+	upstream = flakyServer(t, &hits, http.Header{HeaderRetryAfter: {"1"}}, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	assert.NoError(t, err)
+	start := time.Now()
+	_, err = client.Do(r)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestRetryTransportErrors(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := upstream.URL
+	upstream.Close()
+
+	var retries int32
+	client, err := NewClient(ClientOpts{Retry: &RetryOpts{
+		MaxAttempts: 4,
+		BaseDelay:   time.Millisecond,
+		OnRetry: func(r *http.Request, attempt int, wait time.Duration, res *http.Response, err error) {
+			assert.Nil(t, res)
+			assert.Error(t, err)
+			atomic.AddInt32(&retries, 1)
+		},
+	}})
+	assert.NoError(t, err)
+	_, err = client.Get(url)
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), retries)
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := &RetryBudget{Ratio: 0.5, Min: 1, Window: time.Hour}
+	for i := 0; i < 4; i++ {
+		budget.request()
+	}
+	assert.True(t, budget.retry())
+	assert.True(t, budget.retry())
+	assert.False(t, budget.retry())
+	budget.request()
+	budget.request()
+	assert.True(t, budget.retry())
+	assert.False(t, budget.retry())
+
+	budget = &RetryBudget{Window: time.Millisecond}
+	for i := 0; i < 10; i++ {
+		assert.True(t, budget.retry())
+	}
+	assert.False(t, budget.retry())
+	time.Sleep(2 * time.Millisecond)
+	assert.True(t, budget.retry())
+
+	var hits int32
+	upstream := flakyServer(t, &hits, nil, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	client, err := NewClient(ClientOpts{Retry: &RetryOpts{
+		BaseDelay: time.Millisecond,
+		Budget:    &RetryBudget{Ratio: 0.1, Min: 1, Window: time.Hour},
+	}})
+	assert.NoError(t, err)
+	res, err := client.Get(upstream.URL)
+	assert.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, int32(2), hits)
+}
+
+func TestRetryAfter(t *testing.T) {
+	wait, ok := retryAfter("3")
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, wait)
+
+	wait, ok = retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, float64(time.Minute), float64(wait), float64(2*time.Second))
+
+	wait, ok = retryAfter("Sun, 01 May 2022 00:00:00 GMT")
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), wait)
+
+	_, ok = retryAfter("soon")
+	assert.False(t, ok)
+	_, ok = retryAfter("")
+	assert.False(t, ok)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	opts := RetryOpts{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}.withDefaults()
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, opts.backoff(1), 10*time.Millisecond)
+		assert.LessOrEqual(t, opts.backoff(3), 40*time.Millisecond)
+		assert.LessOrEqual(t, opts.backoff(40), 50*time.Millisecond)
+	}
+}
@@ -0,0 +1,185 @@
+package bifrost
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// StreamIterator yields the items of a streamed response one at a time,
+// ok is false once there are no more.
+type StreamIterator func(ctx context.Context) (item interface{}, ok bool, err error)
+
+// StreamChannel iterates over the items received from ch until it is closed.
+func StreamChannel(ch <-chan interface{}) StreamIterator {
+	return func(ctx context.Context) (interface{}, bool, error) {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case item, ok := <-ch:
+			return item, ok, nil
+		}
+	}
+}
+
+// StreamSlice iterates over the items of a slice.
+func StreamSlice(items []interface{}) StreamIterator {
+	n := 0
+	return func(ctx context.Context) (interface{}, bool, error) {
+		if n >= len(items) {
+			return nil, false, nil
+		}
+		n++
+		return items[n-1], true, nil
+	}
+}
+
+type StreamOpts struct {
+	// Key of the data member holding the streamed items.
+	Key string
+	// FlushEvery is the number of items written between two flushes, 100 by default.
+	FlushEvery int
+	// Pagination is called once every item has been written, it may return
+	// a Pagination built from the last item.
+	Pagination func() interface{}
+}
+
+// ResponseJSONStream writes the response envelope with the items of next as
+// the data.<Key> array, encoding them one at a time and flushing periodically.
+// Since the status has already been sent when an item fails, a failure is
+// reported as an "error" member holding the error Meta, after "pagination",
+// and in the X-Stream-Error trailer. The failure is still returned, which
+// HandlerAdapter then only logs.
+func ResponseJSONStream(w http.ResponseWriter, r *http.Request, code int, opts StreamOpts, next StreamIterator) error {
+	if opts.FlushEvery < 1 {
+		opts.FlushEvery = 100
+	}
+	version := interface{}(Version{
+		Label:  "v1",
+		Number: "0.1.0",
+	})
+	if ver, ok := r.Context().Value(CtxVersion).(Version); ok {
+		version = ver
+	}
+	w.Header().Set(HeaderContentType, MIMEApplicationJSONCharsetUTF8)
+	w.Header().Set("Trailer", HeaderXStreamError)
+	w.WriteHeader(code)
+
+	bw := bufio.NewWriter(w)
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+
+	sw := &streamWriter{w: bw}
+	sw.raw(`{"version":`)
+	sw.value(version)
+	sw.raw(`,"meta":`)
+	sw.value(Meta{Code: http.StatusText(code), Warning: deprecationWarning(r), RequestID: GetRequestID(r.Context())})
+	sw.raw(`,"data":{`)
+	sw.value(opts.Key)
+	sw.raw(`:[`)
+	if sw.err != nil {
+		return sw.err
+	}
+
+	var streamErr error
+	for n := 0; ; n++ {
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		item, ok, err := next(r.Context())
+		if err != nil {
+			streamErr = err
+			break
+		}
+		if !ok {
+			break
+		}
+		b, err := json.Marshal(item)
+		if err != nil {
+			streamErr = err
+			break
+		}
+		if n > 0 {
+			sw.raw(",")
+		}
+		sw.bytes(b)
+		if sw.err != nil {
+			return sw.err
+		}
+		if (n+1)%opts.FlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	sw.raw(`]},"pagination":`)
+	var pagination interface{} = make(map[string]interface{})
+	if opts.Pagination != nil && streamErr == nil {
+		pagination = opts.Pagination()
+	}
+	sw.value(pagination)
+	if streamErr != nil {
+		e := AsError(streamErr)
+		sw.raw(`,"error":`)
+		sw.value(Meta{
+			Code:      strconv.Itoa(e.StatusCode()),
+			Type:      http.StatusText(e.StatusCode()),
+			Message:   e.message(),
+			ErrorCode: e.Code,
+			Details:   e.Details,
+		})
+		w.Header().Set(HeaderXStreamError, e.message())
+	}
+	sw.raw("}\n")
+	if sw.err != nil {
+		return sw.err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return streamErr
+}
+
+// streamWriter keeps the first write error so the envelope can be written
+// without checking every call.
+type streamWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (s *streamWriter) raw(str string) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = io.WriteString(s.w, str)
+}
+
+func (s *streamWriter) bytes(b []byte) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.w.Write(b)
+}
+
+func (s *streamWriter) value(v interface{}) {
+	if s.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.bytes(b)
+}
@@ -0,0 +1,93 @@
+package bifrost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exportRow struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestResponseJSONStream(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/export", nil)
+	assert.NoError(t, err)
+	SemanticVersion(r, "v2", "2.0.0")
+	w := httptest.NewRecorder()
+
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for n := 1; n <= 250; n++ {
+			ch <- exportRow{ID: n, Name: "warung"}
+		}
+	}()
+	err = ResponseJSONStream(w, r, http.StatusOK, StreamOpts{
+		Key:        "rows",
+		FlushEvery: 100,
+		Pagination: func() interface{} {
+			return Pagination{Limit: 250, NextCursor: 250}
+		},
+	}, StreamChannel(ch))
+	assert.NoError(t, err)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, MIMEApplicationJSONCharsetUTF8, w.Header().Get(HeaderContentType))
+
+	var actual struct {
+		Version Version `json:"version"`
+		Meta    Meta    `json:"meta"`
+		Data    struct {
+			Rows []exportRow `json:"rows"`
+		} `json:"data"`
+		Pagination Pagination `json:"pagination"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, Version{Label: "v2", Number: "2.0.0"}, actual.Version)
+	assert.Equal(t, http.StatusText(http.StatusOK), actual.Meta.Code)
+	assert.Len(t, actual.Data.Rows, 250)
+	assert.Equal(t, exportRow{ID: 250, Name: "warung"}, actual.Data.Rows[249])
+	assert.Equal(t, 250, actual.Pagination.Limit)
+}
+
+func TestResponseJSONStreamEmpty(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/export", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	assert.NoError(t, ResponseJSONStream(w, r, http.StatusOK, StreamOpts{Key: "rows"}, StreamSlice(nil)))
+	assert.JSONEq(t, `{"version":{"label":"v1","number":"0.1.0"},"meta":{"code":"OK"},"data":{"rows":[]},"pagination":{}}`, w.Body.String())
+}
+
+func TestResponseJSONStreamFailure(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/export", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+
+	errUpstream := NewError(http.StatusServiceUnavailable, "warehouse_unavailable", "warehouse is unavailable")
+	n := 0
+	HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		return ResponseJSONStream(w, r, http.StatusOK, StreamOpts{Key: "rows"}, func(ctx context.Context) (interface{}, bool, error) {
+			n++
+			if n > 2 {
+				return nil, false, errUpstream
+			}
+			return exportRow{ID: n}, true, nil
+		})
+	}).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"version":{"label":"v1","number":"0.1.0"},
+		"meta":{"code":"OK"},
+		"data":{"rows":[{"id":1,"name":""},{"id":2,"name":""}]},
+		"pagination":{},
+		"error":{"code":"503","error_type":"Service Unavailable","error_message":"warehouse is unavailable","error_code":"warehouse_unavailable"}
+	}`, w.Body.String())
+	assert.Equal(t, "warehouse is unavailable", w.Result().Trailer.Get(HeaderXStreamError))
+}
@@ -0,0 +1,145 @@
+package bifrost
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Process runs until ctx is cancelled and reports why it stopped.
+type Process func(ctx context.Context) error
+
+// Process wraps the http server and its handler for a Supervisor.
+func (s *Server) Process(handler http.Handler) Process {
+	return func(ctx context.Context) error {
+		return s.RunContext(ctx, handler)
+	}
+}
+
+// Process wraps the grpc server and its registration callback for a Supervisor.
+func (g *GRpc) Process(callback GRPCCallback) Process {
+	return func(ctx context.Context) error {
+		return g.RunContext(ctx, callback)
+	}
+}
+
+// SupervisorError aggregates the errors returned by supervised processes.
+type SupervisorError struct {
+	Errors []error
+}
+
+func (e *SupervisorError) Error() string {
+	msg := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msg = append(msg, err.Error())
+	}
+	return strings.Join(msg, "; ")
+}
+
+// Is lets errors.Is match any of the aggregated errors, which the Go 1.18
+// errors package does not unwrap from a slice.
+func (e *SupervisorError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As lets errors.As find target in the first of the aggregated errors which
+// holds one.
+func (e *SupervisorError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Supervisor starts several processes concurrently and stops all of them
+// once one fails or the supervisor itself is interrupted.
+type Supervisor struct {
+	processes []Process
+	// Logger logs why the processes are stopped, the global logger of
+	// zerolog/log when nil.
+	Logger *zerolog.Logger
+}
+
+func NewSupervisor(processes ...Process) *Supervisor {
+	return &Supervisor{processes: processes}
+}
+
+// Add registers a process, it is started after those already registered.
+func (s *Supervisor) Add(p Process) {
+	s.processes = append(s.processes, p)
+}
+
+// Run supervises the processes until the process receives SIGINT or SIGTERM.
+func (s *Supervisor) Run() error {
+	ctx, cancel := SignalContext(context.Background())
+	defer cancel()
+	return s.RunContext(ctx)
+}
+
+// RunContext supervises the processes until ctx is cancelled or one of them
+// returns. The remaining processes are then stopped one by one, in the
+// reverse order of registration, and their errors are aggregated.
+func (s *Supervisor) RunContext(ctx context.Context) error {
+	type result struct {
+		idx int
+		err error
+	}
+
+	n := len(s.processes)
+	if n < 1 {
+		return nil
+	}
+	cancels := make([]context.CancelFunc, n)
+	exited := make([]bool, n)
+	results := make(chan result, n)
+	for i, p := range s.processes {
+		pCtx, cancel := context.WithCancel(context.Background())
+		cancels[i] = cancel
+		go func(i int, p Process, ctx context.Context) {
+			results <- result{idx: i, err: p(ctx)}
+		}(i, p, pCtx)
+	}
+
+	logger := log.Logger
+	if s.Logger != nil {
+		logger = *s.Logger
+	}
+	errs := make([]error, 0)
+	collect := func(res result) {
+		exited[res.idx] = true
+		if res.err != nil {
+			errs = append(errs, res.err)
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Info().Err(ctx.Err()).Msg("Supervisor interrupted through context")
+	case res := <-results:
+		collect(res)
+		logger.Info().Int("process", res.idx).Err(res.err).Msg("Supervised process exited")
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		cancels[i]()
+		for !exited[i] {
+			collect(<-results)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &SupervisorError{Errors: errs}
+	}
+	return nil
+}
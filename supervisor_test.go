@@ -0,0 +1,136 @@
+package bifrost
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	rpc "google.golang.org/grpc"
+)
+
+type stopRecorder struct {
+	sync.Mutex
+	order []int
+}
+
+func (s *stopRecorder) process(idx int, err error) Process {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		s.Lock()
+		defer s.Unlock()
+		s.order = append(s.order, idx)
+		return err
+	}
+}
+
+func TestSupervisorStopsInReverseOrder(t *testing.T) {
+	rec := &stopRecorder{}
+	sup := NewSupervisor(rec.process(0, nil), rec.process(1, nil))
+	sup.Add(rec.process(2, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, sup.RunContext(ctx))
+	assert.Equal(t, []int{2, 1, 0}, rec.order)
+}
+
+func TestSupervisorStopsOthersOnFailure(t *testing.T) {
+	rec := &stopRecorder{}
+	errListen := errors.New("listen failed")
+	errStop := errors.New("stop failed")
+	sup := NewSupervisor(
+		rec.process(0, errStop),
+		func(ctx context.Context) error {
+			return errListen
+		},
+		rec.process(2, nil),
+	)
+
+	err := sup.RunContext(context.Background())
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, errListen))
+	assert.True(t, errors.Is(err, errStop))
+	var supErr *SupervisorError
+	assert.True(t, errors.As(err, &supErr))
+	assert.Len(t, supErr.Errors, 2)
+	assert.Equal(t, []int{2, 0}, rec.order)
+}
+
+func TestSupervisorLogger(t *testing.T) {
+	global := captureLog(t)
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	errListen := errors.New("listen failed")
+	sup := NewSupervisor(func(ctx context.Context) error {
+		return errListen
+	})
+	sup.Logger = &logger
+
+	assert.Error(t, sup.RunContext(context.Background()))
+	assert.Contains(t, buf.String(), `"process":0,"error":"listen failed","message":"Supervised process exited"`)
+	assert.Empty(t, global.String())
+}
+
+func TestSupervisorErrorIsAs(t *testing.T) {
+	errListen := errors.New("listen failed")
+	errStop := fmt.Errorf("stop failed: %w", &os.PathError{Op: "close", Path: "/tmp/orders.sock", Err: os.ErrClosed})
+	err := &SupervisorError{Errors: []error{errListen, errStop}}
+
+	// called directly, as the errors package of Go 1.18 does
+	assert.True(t, err.Is(errListen))
+	assert.True(t, err.Is(os.ErrClosed))
+	assert.False(t, err.Is(os.ErrNotExist))
+	var pathErr *os.PathError
+	assert.True(t, err.As(&pathErr))
+	assert.Equal(t, "/tmp/orders.sock", pathErr.Path)
+	var supErr *SupervisorError
+	assert.False(t, err.As(&supErr))
+}
+
+func TestSupervisorHttpAndGRPC(t *testing.T) {
+	httpPort, err := findOpenPort()
+	if err != nil {
+		assert.Fail(t, "could not find a testing port")
+	}
+	grpcPort, err := findOpenPort()
+	if err != nil {
+		assert.Fail(t, "could not find a testing port")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	web := NewServerMux(ServeOpts{Port: WebPort(httpPort), TimeOut: WebTimeOut(1)})
+	rpcSrv := NewServerGRPC(GRPCOpts{Port: GRPCPort(grpcPort)})
+	sup := NewSupervisor(
+		web.Process(http.NewServeMux()),
+		rpcSrv.Process(func(s *rpc.Server) {}),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- sup.RunContext(ctx)
+	}()
+	waitForPort(t, httpPort)
+	waitForPort(t, grpcPort)
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d", httpPort))
+	assert.NoError(t, err)
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not stop after context cancellation")
+	}
+}
@@ -1,23 +1,22 @@
 package bifrost
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"go.opentelemetry.io/otel/trace"
-	"io/ioutil"
-	"net/http"
-	"runtime/debug"
-	"strings"
-
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/rs/zerolog/log"
 )
 
 type tracerContext struct {
@@ -30,27 +29,163 @@ func (r *tracerContext) String() string {
 
 var TracerContext = tracerContext{Name: "context Respond"}
 
+// Propagation is a format of the trace context carried by request headers.
+type Propagation int
+
+const (
+	// PropagationTraceContext is the W3C traceparent and tracestate headers.
+	PropagationTraceContext Propagation = iota
+	// PropagationBaggage is the W3C baggage header.
+	PropagationBaggage
+	// PropagationB3 is the single b3 header of Zipkin.
+	PropagationB3
+	// PropagationB3Multi is the X-B3-* headers of Zipkin.
+	PropagationB3Multi
+	// PropagationJaeger is the uber-trace-id header of Jaeger.
+	PropagationJaeger
+)
+
+func (p Propagation) propagator() propagation.TextMapPropagator {
+	switch p {
+	case PropagationBaggage:
+		return propagation.Baggage{}
+	case PropagationB3:
+		return b3.New(b3.WithInjectEncoding(b3.B3SingleHeader))
+	case PropagationB3Multi:
+		return b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader))
+	case PropagationJaeger:
+		return jaeger.Jaeger{}
+	default:
+		return propagation.TraceContext{}
+	}
+}
+
+// TracerOpts configures the middleware returned by NewHttpTracer.
+type TracerOpts struct {
+	// Propagations are the formats the incoming trace context is read
+	// from, the global propagator of otel when empty.
+	Propagations []Propagation
+	// SpanName names the span of a request, "METHOD /path" by default. It is
+	// called again once the request is served, so SpanNameRoutePattern can
+	// name it after the route chi matched.
+	SpanName func(r *http.Request) string
+	// Filters tell whether a request is traced, it is when all of them
+	// return true.
+	Filters []func(r *http.Request) bool
+	// TracerProvider starts the spans, the global provider of otel when nil.
+	TracerProvider trace.TracerProvider
+	// MaxBodySize is the number of bytes of the request body captured in the
+	// span, none when 0: bodies are left to NewAuditLogger unless asked for.
+	// Larger bodies are only reported as truncated.
+	MaxBodySize int
+	// Redact hides the sensitive values of the captured body, the
+	// DefaultRedactedFields are redacted when nil.
+	Redact BodyRedactor
+}
+
+func (o TracerOpts) spanName(r *http.Request) string {
+	if o.SpanName != nil {
+		return o.SpanName(r)
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+func (o TracerOpts) traced(r *http.Request) bool {
+	for _, filter := range o.Filters {
+		if !filter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o TracerOpts) tracer() trace.Tracer {
+	if o.TracerProvider != nil {
+		return o.TracerProvider.Tracer("http.tracer")
+	}
+	return otel.Tracer("http.tracer")
+}
+
+func (o TracerOpts) redact(contentType string, body []byte) string {
+	if o.Redact == nil {
+		return string(RedactFields(DefaultRedactedFields...)(contentType, body))
+	}
+	return string(o.Redact(contentType, body))
+}
+
+// SpanNameRoutePattern names spans after the route pattern chi matched,
+// e.g. "GET /orders/{id}", or after the path until a route is matched.
+func SpanNameRoutePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return r.Method + " " + pattern
+		}
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// SkipPaths is a filter leaving the requests of the paths untraced, such as
+// health checks.
+func SkipPaths(paths ...string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		for _, path := range paths {
+			if r.URL.Path == path {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (o TracerOpts) propagator() propagation.TextMapPropagator {
+	if len(o.Propagations) < 1 {
+		return otel.GetTextMapPropagator()
+	}
+	propagators := make([]propagation.TextMapPropagator, 0, len(o.Propagations))
+	for _, p := range o.Propagations {
+		propagators = append(propagators, p.propagator())
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...)
+}
+
+// HttpTracer traces the requests, continuing the trace of the incoming
+// context read by the global propagator of otel.
 func HttpTracer(next http.Handler) http.Handler {
+	return NewHttpTracer(TracerOpts{})(next)
+}
+
+// NewHttpTracer returns a middleware tracing the requests, a span is started
+// for each of them as the child of the incoming trace context, if any.
+func NewHttpTracer(opts TracerOpts) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return httpTracer(opts, next)
+	}
+}
+
+func httpTracer(tracerOpts TracerOpts, next http.Handler) http.Handler {
+	propagator := tracerOpts.propagator()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		operation := r.Method + " " + r.URL.Path
-		opts := append(
-			[]trace.SpanStartOption{
-				trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", r)...),
-				trace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(r)...),
-				trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(operation, "", r)...),
-			},
-		) // start with the configured options
-
-		carrier := http.Header{}
-		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(carrier))
-		tr := otel.Tracer("http.tracer")
+		if !tracerOpts.traced(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		operation := tracerOpts.spanName(r)
+		opts := []trace.SpanStartOption{
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", r)...),
+			trace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(r)...),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(operation, "", r)...),
+		} // start with the configured options
+
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		tr := tracerOpts.tracer()
 		ctxSpan, span := tr.Start(ctx, operation, opts...)
 		defer span.End()
 
 		defer func() {
 			if err := recover(); err != nil {
 				span.SetStatus(codes.Error, "recover")
-				span.RecordError(err.(error))
+				span.RecordError(fmt.Errorf("%v", err))
 				span.SetAttributes(attribute.Key("event").String("error"))
 				span.SetAttributes(attribute.Key("error.kind").String("panic"))
 				span.SetAttributes(attribute.Key("stack").String(string(debug.Stack())))
@@ -60,67 +195,25 @@ func HttpTracer(next http.Handler) http.Handler {
 			}
 		}()
 
-		span.SetAttributes(attribute.String("request.id", r.Header.Get("X-Request-Id")))
+		span.SetAttributes(attribute.String("request.id", requestID(r)))
 
-		// There's nothing we can do with any errors here.
-		otel.GetTextMapPropagator().Inject(ctxSpan, propagation.HeaderCarrier(carrier))
 		r = r.WithContext(trace.ContextWithSpan(ctxSpan, span))
 
-		// check content length
-		if r.ContentLength > 0 {
-			// Request
-			var buf []byte
-			if r.Body != nil { // Read
-				buf, _ = ioutil.ReadAll(r.Body)
-			}
-
-			response := make(map[string]interface{})
-
-			// get content-type
+		if r.ContentLength != 0 && tracerOpts.MaxBodySize > 0 {
 			cType := r.Header.Get(HeaderContentType)
-
-			switch {
-			case strings.HasPrefix(cType, MIMETextPlain):
-			case strings.HasPrefix(cType, MIMEApplicationForm):
-				if err := r.ParseForm(); err != nil {
-					log.Error().Err(ErrBadRequest(w, r, err)).Msg("Request body contains badly-formed form-urlencoded")
-					_ = ResponseJSONPayload(w, r, http.StatusBadRequest, nil)
-					return
+			if !strings.HasPrefix(cType, MIMEMultipartForm) {
+				body, truncated := peekBody(r, tracerOpts.MaxBodySize)
+				if truncated {
+					span.SetAttributes(attribute.Bool("resource.payload.truncated", true))
+				} else if len(body) > 0 {
+					span.SetAttributes(attribute.String("resource.payload", tracerOpts.redact(cType, body)))
 				}
-
-				log.Info().
-					Str(HeaderContentType, cType).
-					Str("body", string(buf)).Msg("resource payload")
-				span.SetAttributes(attribute.String("resource.payload", string(buf)))
-			case strings.HasPrefix(cType, MIMEMultipartForm):
-			case strings.HasPrefix(cType, MIMEApplicationJSON):
-				// b := http.MaxBytesReader(w, b, 1048576)
-				body := json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(buf)))
-				body.DisallowUnknownFields()
-
-				if err := body.Decode(&response); err != nil {
-					log.Error().Err(ErrBadRequest(w, r, err)).Msg("Request body contains badly-formed JSON")
-					_ = ResponseJSONPayload(w, r, http.StatusBadRequest, nil)
-					return
-				}
-				log.Info().
-					Str(HeaderContentType, cType).
-					Interface("body", response).Msg("resource payload")
-				span.SetAttributes(attribute.String("resource.payload", string(buf)))
-			default:
-				log.Info().
-					Str(HeaderContentType, cType).
-					Str("body", string(buf)).Msg("resource payload")
-				span.SetAttributes(attribute.String("resource.payload", string(buf)))
 			}
-			r.Body = ioutil.NopCloser(bytes.NewBuffer(buf))
 		}
 
-		log.Info().Msgf("tracing form middleware endpoint %s", r.URL.Path)
-
 		traceID := "trace-bifrost-id"
-		if traceID := r.Header.Get(HeaderUberTraceId); len(traceID) > 0 {
-			traceID = strings.Split(traceID, ":")[0]
+		if uberTraceID := r.Header.Get(HeaderUberTraceId); len(uberTraceID) > 0 {
+			traceID = strings.Split(uberTraceID, ":")[0]
 		}
 		sc := trace.SpanContextFromContext(r.Context())
 		if sc.TraceID().IsValid() || sc.SpanID().IsValid() {
@@ -134,6 +227,7 @@ func HttpTracer(next http.Handler) http.Handler {
 		// pass the span through the request context and serve the request to the next middleware
 		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 		next.ServeHTTP(ww, r)
+		span.SetName(tracerOpts.spanName(r))
 
 		// set the status code
 		status := ww.Status()
@@ -0,0 +1,227 @@
+package bifrost
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	upstreamTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	upstreamSpanID  = "00f067aa0ba902b7"
+)
+
+func withSpanExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(provider) })
+	return exporter
+}
+
+func TestHttpTracerPropagation(t *testing.T) {
+	cases := []struct {
+		name        string
+		propagation Propagation
+		header      http.Header
+	}{
+		{"tracecontext", PropagationTraceContext, http.Header{
+			"Traceparent": {"00-" + upstreamTraceID + "-" + upstreamSpanID + "-01"},
+		}},
+		{"b3 single", PropagationB3, http.Header{
+			"B3": {upstreamTraceID + "-" + upstreamSpanID + "-1"},
+		}},
+		{"b3 multi", PropagationB3Multi, http.Header{
+			"X-B3-Traceid": {upstreamTraceID},
+			"X-B3-Spanid":  {upstreamSpanID},
+			"X-B3-Sampled": {"1"},
+		}},
+		{"jaeger", PropagationJaeger, http.Header{
+			HeaderUberTraceId: {upstreamTraceID + ":" + upstreamSpanID + ":0:1"},
+		}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := withSpanExporter(t)
+			handler := NewHttpTracer(TracerOpts{Propagations: []Propagation{tt.propagation}})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, span := otel.Tracer("test").Start(r.Context(), "load orders")
+					span.End()
+					w.WriteHeader(http.StatusOK)
+				}))
+
+			r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, upstreamTraceID, w.Header().Get(HeaderXTraceId))
+			spans := exporter.GetSpans()
+			assert.Len(t, spans, 2)
+			child, server := spans[0], spans[1]
+			assert.Equal(t, "GET /orders", server.Name)
+			assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+			assert.Equal(t, upstreamTraceID, server.SpanContext.TraceID().String())
+			assert.Equal(t, upstreamSpanID, server.Parent.SpanID().String())
+			assert.True(t, server.Parent.IsRemote())
+			assert.Equal(t, server.SpanContext.TraceID(), child.SpanContext.TraceID())
+			assert.Equal(t, server.SpanContext.SpanID(), child.Parent.SpanID())
+		})
+	}
+}
+
+func TestHttpTracerBaggage(t *testing.T) {
+	withSpanExporter(t)
+	var member baggage.Member
+	handler := NewHttpTracer(TracerOpts{Propagations: []Propagation{PropagationTraceContext, PropagationBaggage}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			member = baggage.FromContext(r.Context()).Member("tenant")
+		}))
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Header.Set("Traceparent", "00-"+upstreamTraceID+"-"+upstreamSpanID+"-01")
+	r.Header.Set("Baggage", "tenant=kubuskotak")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "kubuskotak", member.Value())
+}
+
+func TestHttpTracerRootSpan(t *testing.T) {
+	exporter := withSpanExporter(t)
+	var traceID interface{}
+	handler := HttpTracer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID = r.Context().Value(TracerContext)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Header.Set("Traceparent", "00-"+upstreamTraceID+"-"+upstreamSpanID+"-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.Equal(t, spans[0].SpanContext.TraceID().String(), traceID)
+	assert.Equal(t, traceID, w.Header().Get(HeaderXTraceId))
+}
+
+func TestHttpTracerUberTraceID(t *testing.T) {
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	defer otel.SetTracerProvider(provider)
+
+	handler := HttpTracer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.False(t, trace.SpanContextFromContext(r.Context()).IsValid())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Header.Set(HeaderUberTraceId, upstreamTraceID+":"+upstreamSpanID+":0:1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, upstreamTraceID, w.Header().Get(HeaderXTraceId))
+}
+
+func TestHttpTracerOpts(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	router := chi.NewRouter()
+	router.Use(NewHttpTracer(TracerOpts{
+		SpanName:       SpanNameRoutePattern,
+		Filters:        []func(r *http.Request) bool{SkipPaths("/healthz")},
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		MaxBodySize:    64,
+	}))
+	router.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, w.Header().Get(HeaderXTraceId))
+	})
+	router.Post("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	for _, body := range []string{
+		`{"user":"gopher","password":"hunter2"}`,
+		`{"user":"gopher","password":"hunter2","note":"leave it at the front door"}`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/orders/42", strings.NewReader(body))
+		r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	for body, contentType := range map[string]string{
+		`{"password":"hunter2"`: MIMEApplicationJSON,
+		`password=hunter2`:      MIMETextPlain,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/orders/42", strings.NewReader(body))
+		r.Header.Set(HeaderContentType, contentType)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 4)
+	assert.Equal(t, "POST /orders/{id}", spans[0].Name)
+	attrs := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, attr := range span.Attributes {
+			m[attr.Key] = attr.Value
+		}
+		return m
+	}
+	assert.Equal(t, `{"password":"[REDACTED]","user":"gopher"}`, attrs(spans[0])["resource.payload"].AsString())
+	assert.NotContains(t, attrs(spans[1]), attribute.Key("resource.payload"))
+	assert.True(t, attrs(spans[1])["resource.payload.truncated"].AsBool())
+	// bodies the redactor cannot parse are hidden whole
+	assert.Equal(t, Redacted, attrs(spans[2])["resource.payload"].AsString())
+	assert.Equal(t, Redacted, attrs(spans[3])["resource.payload"].AsString())
+}
+
+func TestHttpTracerKeepsOutcome(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	for _, body := range []string{`[1,2,3]`, `"scalar"`, `{"unknown":true}`, `{"broken":`} {
+		var received []byte
+		handler := NewHttpTracer(TracerOpts{
+			TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+			MaxBodySize:    4,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusCreated, w.Code, body)
+		assert.Equal(t, body, string(received))
+	}
+}
+
+func TestHttpTracerWithoutBody(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	handler := NewHttpTracer(TracerOpts{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "token=abc", string(received))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`token=abc`))
+	r.Header.Set(HeaderContentType, MIMEApplicationForm)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	for _, attr := range spans[0].Attributes {
+		assert.NotContains(t, []attribute.Key{"resource.payload", "resource.payload.truncated"}, attr.Key)
+	}
+}
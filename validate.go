@@ -0,0 +1,356 @@
+package bifrost
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrValidation is the error HandlerAdapter answers for ValidationErrors.
+var ErrValidation = NewError(http.StatusUnprocessableEntity, "validation_failed", "request validation failed")
+
+// ValidationErrors lists every field of a request failing validation.
+type ValidationErrors []ErrorDetail
+
+func (v ValidationErrors) Error() string {
+	msg := make([]string, 0, len(v))
+	for _, d := range v {
+		msg = append(msg, d.Field+": "+d.Message)
+	}
+	return strings.Join(msg, "; ")
+}
+
+// As lets errors.As find ValidationErrors as an *Error based on ErrValidation,
+// with one detail per failing field.
+func (v ValidationErrors) As(target interface{}) bool {
+	e, ok := target.(**Error)
+	if !ok {
+		return false
+	}
+	*e = ErrValidation.WithDetails(v...).Wrap(v)
+	return true
+}
+
+// Validate checks the `validate` struct tags of i, a struct or a pointer to
+// one. Rules are separated by commas:
+//
+//	required      the value must not be the zero value
+//	omitempty     the other rules are skipped when the value is the zero value
+//	min=n, max=n  bounds of a number, or of the length of a string, slice or map
+//	len=n         exact length of a string, slice or map
+//	enum=a|b|c    the value must be one of the listed ones
+//	email         the string must be an e-mail address
+//	regex=expr    the string must match expr, it takes the rest of the tag
+//
+// A nil pointer is only checked against required. Nested structs, slices
+// and maps are validated recursively. Field paths use the names encoding/json
+// gives to the fields, e.g. items[0].unit_price: the name of the json tag,
+// else of the path, query, header or cookie tag, else the name of the field.
+//
+// The tags of a type are parsed once. A malformed one, an unknown rule or a
+// bound which is not a number, is returned as an error of its own rather
+// than as ValidationErrors.
+func Validate(i interface{}) error {
+	errs := make(ValidationErrors, 0)
+	if err := validateNested(reflect.ValueOf(i), "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateNested(v reflect.Value, path string, errs *ValidationErrors) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			return nil
+		}
+		return validateStruct(v, path, errs)
+	case reflect.Slice, reflect.Array:
+		for n := 0; n < v.Len(); n++ {
+			if err := validateNested(v.Index(n), fmt.Sprintf("%s[%d]", path, n), errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			if err := validateNested(v.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface()), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) error {
+	fields, err := structRules(v.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		// embedded structs are flattened, as encoding/json does
+		if f.embedded {
+			if err := validateNested(v.Field(f.index), prefix, errs); err != nil {
+				return err
+			}
+			continue
+		}
+		path := f.name
+		if prefix != "" {
+			path = prefix + "." + f.name
+		}
+		if f.required || f.omitEmpty || len(f.rules) > 0 {
+			next, err := f.validate(v.Field(f.index), path, errs)
+			if err != nil {
+				return err
+			}
+			if !next {
+				continue
+			}
+		}
+		if err := validateNested(v.Field(f.index), path, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldRules are the parsed `validate` tag of a field.
+type fieldRules struct {
+	index     int
+	name      string
+	embedded  bool
+	required  bool
+	omitEmpty bool
+	rules     []rule
+}
+
+type rule struct {
+	name    string
+	param   string
+	bound   float64
+	options []string
+	re      *regexp.Regexp
+}
+
+type parsedRules struct {
+	fields []fieldRules
+	err    error
+}
+
+var rulesCache sync.Map
+
+// structRules returns the fields of t to validate, parsing their tags on
+// the first call only.
+func structRules(t reflect.Type) ([]fieldRules, error) {
+	if cached, ok := rulesCache.Load(t); ok {
+		p := cached.(*parsedRules)
+		return p.fields, p.err
+	}
+	p := &parsedRules{}
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name, tagged := fieldName(f)
+		if name == "-" {
+			continue
+		}
+		field := fieldRules{index: n, name: name, embedded: f.Anonymous && !tagged}
+		if tag, ok := f.Tag.Lookup("validate"); ok && tag != "" && !field.embedded {
+			if err := field.parse(f.Type, tag); err != nil {
+				p.err = fmt.Errorf("bifrost: invalid validate tag of %s.%s: %w", t, f.Name, err)
+				break
+			}
+		}
+		p.fields = append(p.fields, field)
+	}
+	if p.err != nil {
+		p.fields = nil
+	}
+	rulesCache.Store(t, p)
+	return p.fields, p.err
+}
+
+// fieldName is the name encoding/json gives to the field, unless a bind tag
+// names it, and whether a tag does.
+func fieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "-", true
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	for _, source := range []string{BindPath, BindQuery, BindHeader, BindCookie} {
+		if name := f.Tag.Get(source); name != "" {
+			return name, true
+		}
+	}
+	return f.Name, false
+}
+
+func splitRules(tag string) []string {
+	rules := make([]string, 0)
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(rules, tag)
+		}
+		idx := strings.Index(tag, ",")
+		if idx < 0 {
+			return append(rules, tag)
+		}
+		rules = append(rules, tag[:idx])
+		tag = tag[idx+1:]
+	}
+	return rules
+}
+
+func (f *fieldRules) parse(t reflect.Type, tag string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, s := range splitRules(tag) {
+		r := rule{name: s}
+		if idx := strings.Index(s, "="); idx >= 0 {
+			r.name, r.param = s[:idx], s[idx+1:]
+		}
+		switch r.name {
+		case "required":
+			f.required = true
+			continue
+		case "omitempty":
+			f.omitEmpty = true
+			continue
+		case "min", "max", "len":
+			bound, err := strconv.ParseFloat(r.param, 64)
+			if err != nil {
+				return fmt.Errorf("%s=%q is not a number", r.name, r.param)
+			}
+			if _, _, ok := measure(reflect.Zero(t)); !ok && t.Kind() != reflect.Interface {
+				return fmt.Errorf("%s cannot apply to a %s", r.name, t.Kind())
+			}
+			r.bound = bound
+		case "enum":
+			r.options = strings.Split(r.param, "|")
+		case "email":
+		case "regex":
+			re, err := regexp.Compile(r.param)
+			if err != nil {
+				return fmt.Errorf("regex=%q: %w", r.param, err)
+			}
+			r.re = re
+		default:
+			return fmt.Errorf("unknown rule %q", r.name)
+		}
+		f.rules = append(f.rules, r)
+	}
+	return nil
+}
+
+// validate checks the rules of a single field and reports whether its value
+// is worth validating further.
+func (f *fieldRules) validate(v reflect.Value, path string, errs *ValidationErrors) (bool, error) {
+	fail := func(code string, format string, args ...interface{}) bool {
+		*errs = append(*errs, ErrorDetail{Field: path, Code: code, Message: fmt.Sprintf(format, args...)})
+		return false
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if f.required {
+				return fail("required", "is required"), nil
+			}
+			return false, nil
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() {
+		if f.required {
+			return fail("required", "is required"), nil
+		}
+		if f.omitEmpty {
+			return false, nil
+		}
+	}
+
+	ok := true
+	for _, r := range f.rules {
+		switch r.name {
+		case "min", "max", "len":
+			size, unit, measured := measure(v)
+			if !measured {
+				return false, fmt.Errorf("bifrost: cannot apply %s to %s of kind %s", r.name, path, v.Kind())
+			}
+			switch {
+			case r.name == "min" && size < r.bound:
+				ok = fail(r.name, "must be at least %s%s", r.param, unit)
+			case r.name == "max" && size > r.bound:
+				ok = fail(r.name, "must be at most %s%s", r.param, unit)
+			case r.name == "len" && (unit == "" || size != r.bound):
+				ok = fail(r.name, "length must be %s", r.param)
+			}
+		case "enum":
+			value := fmt.Sprint(v.Interface())
+			found := false
+			for _, option := range r.options {
+				if option == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				ok = fail(r.name, "must be one of %s", strings.Join(r.options, ", "))
+			}
+		case "email":
+			addr, err := mail.ParseAddress(v.String())
+			if v.Kind() != reflect.String || err != nil || addr.Address != v.String() {
+				ok = fail(r.name, "must be a valid e-mail address")
+			}
+		case "regex":
+			if v.Kind() != reflect.String || !r.re.MatchString(v.String()) {
+				ok = fail(r.name, "must match %s", r.param)
+			}
+		}
+	}
+	return ok, nil
+}
+
+// measure returns the number compared by min, max and len: the value of a
+// number, or the length of a string, slice or map along with its unit.
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,150 @@
+package bifrost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderItem struct {
+	SKU       string  `json:"sku" validate:"required,regex=^[A-Z]{3}-[0-9]{1,4}$"`
+	Quantity  int     `json:"quantity" validate:"min=1,max=100"`
+	UnitPrice float64 `json:"unit_price" validate:"min=0.01"`
+}
+
+type orderAddress struct {
+	PostalCode string `json:"postal_code" validate:"len=5"`
+}
+
+type order struct {
+	CustomerEmail string        `json:"customer_email" validate:"required,email"`
+	Channel       string        `json:"channel" validate:"omitempty,enum=web|mobile"`
+	Note          *string       `json:"note" validate:"max=5"`
+	Items         []orderItem   `json:"items" validate:"required,max=3"`
+	Shipping      *orderAddress `json:"shipping" validate:"required"`
+	DeliveryDate  string        `validate:"required"`
+}
+
+func TestValidateValid(t *testing.T) {
+	o := order{
+		CustomerEmail: "surya@example.com",
+		Channel:       "web",
+		Items:         []orderItem{{SKU: "ABC-12", Quantity: 2, UnitPrice: 1.5}},
+		Shipping:      &orderAddress{PostalCode: "40115"},
+		DeliveryDate:  "2021-12-29",
+	}
+	assert.NoError(t, Validate(&o))
+}
+
+func TestValidateFieldPaths(t *testing.T) {
+	note := "leave it at the door"
+	o := order{
+		CustomerEmail: "surya",
+		Channel:       "fax",
+		Note:          &note,
+		Items: []orderItem{
+			{SKU: "ABC-12", Quantity: 2, UnitPrice: 1.5},
+			{SKU: "abc", Quantity: 101},
+		},
+		Shipping: &orderAddress{PostalCode: "401"},
+	}
+	err := Validate(o)
+	var verrs ValidationErrors
+	assert.True(t, errors.As(err, &verrs))
+	assert.Equal(t, ValidationErrors{
+		{Field: "customer_email", Code: "email", Message: "must be a valid e-mail address"},
+		{Field: "channel", Code: "enum", Message: "must be one of web, mobile"},
+		{Field: "note", Code: "max", Message: "must be at most 5 characters"},
+		{Field: "items[1].sku", Code: "regex", Message: "must match ^[A-Z]{3}-[0-9]{1,4}$"},
+		{Field: "items[1].quantity", Code: "max", Message: "must be at most 100"},
+		{Field: "items[1].unit_price", Code: "min", Message: "must be at least 0.01"},
+		{Field: "shipping.postal_code", Code: "len", Message: "length must be 5"},
+		{Field: "DeliveryDate", Code: "required", Message: "is required"},
+	}, verrs)
+}
+
+func TestValidateRequired(t *testing.T) {
+	err := Validate(&order{})
+	var verrs ValidationErrors
+	assert.True(t, errors.As(err, &verrs))
+	fields := make([]string, 0)
+	for _, d := range verrs {
+		fields = append(fields, d.Field)
+	}
+	assert.Equal(t, []string{"customer_email", "items", "shipping", "DeliveryDate"}, fields)
+}
+
+func TestBindBodyValidation(t *testing.T) {
+	b, err := json.Marshal(map[string]interface{}{
+		"customer_email": "surya@example.com",
+		"items":          []map[string]interface{}{{"sku": "ABC-1", "quantity": 0}},
+	})
+	assert.NoError(t, err)
+	r, err := http.NewRequest(http.MethodPost, "/", bytes.NewBuffer(b))
+	assert.NoError(t, err)
+	r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+
+	w := httptest.NewRecorder()
+	HandlerAdapter(func(w http.ResponseWriter, r *http.Request) error {
+		var o order
+		return BindBody(r, &o)
+	}).ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var resp struct {
+		Meta Meta `json:"meta"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "validation_failed", resp.Meta.ErrorCode)
+	assert.Equal(t, []ErrorDetail{
+		{Field: "items[0].quantity", Code: "min", Message: "must be at least 1"},
+		{Field: "items[0].unit_price", Code: "min", Message: "must be at least 0.01"},
+		{Field: "shipping", Code: "required", Message: "is required"},
+		{Field: "DeliveryDate", Code: "required", Message: "is required"},
+	}, resp.Meta.Details)
+}
+
+func TestValidateMalformedTags(t *testing.T) {
+	type unknownRule struct {
+		Name string `json:"name" validate:"required,uppercase"`
+	}
+	type badBound struct {
+		Age int `json:"age" validate:"min=abc"`
+	}
+	type badRegex struct {
+		Code string `json:"code" validate:"regex=^[A-Z"`
+	}
+	type unmeasurable struct {
+		Paid bool `json:"paid" validate:"max=1"`
+	}
+	type nested struct {
+		Items []badBound `json:"items"`
+	}
+	for name, tc := range map[string]struct {
+		value interface{}
+		err   string
+	}{
+		"unknown rule": {&unknownRule{Name: "surya"}, `bifrost: invalid validate tag of bifrost.unknownRule.Name: unknown rule "uppercase"`},
+		"bad bound":    {badBound{}, `bifrost: invalid validate tag of bifrost.badBound.Age: min="abc" is not a number`},
+		"bad regex":    {badRegex{}, "bifrost: invalid validate tag of bifrost.badRegex.Code: regex=\"^[A-Z\": "},
+		"unmeasurable": {unmeasurable{}, "bifrost: invalid validate tag of bifrost.unmeasurable.Paid: max cannot apply to a bool"},
+		"nested":       {nested{Items: []badBound{{Age: 1}}}, "bifrost: invalid validate tag of bifrost.badBound.Age"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				var err error
+				assert.NotPanics(t, func() { err = Validate(tc.value) })
+				var verrs ValidationErrors
+				assert.False(t, errors.As(err, &verrs))
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package bifrost
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// VersionStrategy is where a request states the API version it wants.
+type VersionStrategy int
+
+const (
+	// VersionPath reads the first segment of the path, e.g. /v2/orders.
+	VersionPath VersionStrategy = iota
+	// VersionHeader reads the header named by VersioningOpts.Header.
+	VersionHeader
+	// VersionMediaType reads a vendor media type of the Accept header,
+	// e.g. application/vnd.kubuskotak.v2+json.
+	VersionMediaType
+)
+
+const defaultVersionHeader = "Api-Version"
+
+// ErrUnsupportedVersion is answered for a request asking for a version no
+// handler was registered for.
+var ErrUnsupportedVersion = NewError(http.StatusBadRequest, "unsupported_version", "unsupported API version")
+
+var versionLabel = regexp.MustCompile(`^v\d+(\.\d+)*$`)
+
+// VersioningOpts configures how Versions resolves the requested version.
+type VersioningOpts struct {
+	// Strategies are tried in order, path, header and media type by default.
+	Strategies []VersionStrategy
+	// Header is read by VersionHeader, Api-Version by default.
+	Header string
+	// Vendor is the vendor of the media types read by VersionMediaType.
+	Vendor string
+	// Default is the version label of requests stating none, the first
+	// registered version when empty.
+	Default string
+}
+
+type versionRoute struct {
+	version Version
+	handler http.Handler
+}
+
+// Versions dispatches each request to the handler of the API version it asks
+// for, and puts that version in the request context so the response envelope
+// reports it.
+//
+//	versions := bifrost.NewVersions(bifrost.VersioningOpts{Vendor: "kubuskotak"})
+//	versions.Handle(bifrost.Version{Label: "v1", Number: "1.4.0"}, v1Router)
+//	versions.Handle(bifrost.Version{Label: "v2", Number: "2.0.1"}, v2Router)
+type Versions struct {
+	opts   VersioningOpts
+	routes map[string]versionRoute
+	labels []string
+}
+
+func NewVersions(opts VersioningOpts) *Versions {
+	if len(opts.Strategies) < 1 {
+		opts.Strategies = []VersionStrategy{VersionPath, VersionHeader, VersionMediaType}
+	}
+	if opts.Header == "" {
+		opts.Header = defaultVersionHeader
+	}
+	return &Versions{opts: opts, routes: make(map[string]versionRoute)}
+}
+
+// Handle registers the handler serving version v, replacing the one
+// registered before for the same label.
+func (v *Versions) Handle(version Version, handler http.Handler) {
+	label := normalizeVersion(version.Label)
+	if _, ok := v.routes[label]; !ok {
+		v.labels = append(v.labels, label)
+	}
+	v.routes[label] = versionRoute{version: version, handler: handler}
+}
+
+func (v *Versions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	label, source := "", ""
+	for _, strategy := range v.opts.Strategies {
+		switch strategy {
+		case VersionPath:
+			label, source = versionFromPath(r), BindPath
+		case VersionHeader:
+			addVary(w, v.opts.Header)
+			label, source = normalizeVersion(r.Header.Get(v.opts.Header)), BindHeader
+		case VersionMediaType:
+			addVary(w, HeaderAccept)
+			label, source = v.versionFromAccept(r), BindHeader
+		}
+		if label != "" {
+			break
+		}
+	}
+	if label == "" {
+		label = normalizeVersion(v.opts.Default)
+		if label == "" && len(v.labels) > 0 {
+			label = v.labels[0]
+		}
+	}
+
+	route, ok := v.routes[label]
+	if !ok {
+		WriteError(w, r, ErrUnsupportedVersion.WithDetails(ErrorDetail{
+			Field:   source,
+			Code:    "unsupported_version",
+			Message: fmt.Sprintf("version %q is not one of %s", label, strings.Join(v.labels, ", ")),
+		}))
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), CtxVersion, route.version))
+	if source == BindPath {
+		stripVersionPath(r, label)
+	}
+	route.handler.ServeHTTP(w, r)
+}
+
+// versionFromAccept finds the version of the first vendor media type of the
+// Accept header, then rewrites that media type to its structured syntax
+// suffix so that Respond negotiates it as a plain one.
+func (v *Versions) versionFromAccept(r *http.Request) string {
+	if v.opts.Vendor == "" {
+		return ""
+	}
+	prefix := "application/vnd." + strings.ToLower(v.opts.Vendor) + "."
+	label := ""
+	ranges := ParseAccept(r.Header.Get(HeaderAccept))
+	accept := make([]string, 0, len(ranges))
+	for _, mr := range ranges {
+		mediaType := mr.Type
+		if strings.HasPrefix(mediaType, prefix) {
+			found, suffix := strings.TrimPrefix(mediaType, prefix), "json"
+			if idx := strings.Index(found, "+"); idx >= 0 {
+				found, suffix = found[:idx], found[idx+1:]
+			}
+			if label == "" {
+				label = normalizeVersion(found)
+			}
+			mediaType = "application/" + suffix
+		}
+		accept = append(accept, mediaType+";q="+strconv.FormatFloat(mr.Quality, 'f', -1, 64))
+	}
+	if label != "" {
+		r.Header.Set(HeaderAccept, strings.Join(accept, ", "))
+	}
+	return label
+}
+
+func versionFromPath(r *http.Request) string {
+	segment := strings.SplitN(strings.TrimPrefix(routePath(r), "/"), "/", 2)[0]
+	if !versionLabel.MatchString(segment) {
+		return ""
+	}
+	return segment
+}
+
+// routePath is the path left to route, chi keeps it apart once a router is
+// mounted.
+func routePath(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		return rctx.RoutePath
+	}
+	return r.URL.Path
+}
+
+// stripVersionPath removes the version segment from the path left to route.
+func stripVersionPath(r *http.Request, label string) {
+	strip := func(path string) string {
+		path = strings.TrimPrefix(strings.TrimPrefix(path, "/"), label)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		return path
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		rctx.RoutePath = strip(rctx.RoutePath)
+		return
+	}
+	u := *r.URL
+	u.Path = strip(u.Path)
+	u.RawPath = ""
+	r.URL = &u
+}
+
+// normalizeVersion turns "2" and "V2" into "v2".
+func normalizeVersion(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label != "" && label[0] >= '0' && label[0] <= '9' {
+		label = "v" + label
+	}
+	return label
+}
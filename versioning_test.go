@@ -0,0 +1,101 @@
+package bifrost
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func versionedRouter() http.Handler {
+	handler := func(name string) http.Handler {
+		r := chi.NewRouter()
+		r.Get("/orders", func(w http.ResponseWriter, r *http.Request) {
+			_ = Respond(w, r, http.StatusOK, map[string]interface{}{"handler": name})
+		})
+		return r
+	}
+	versions := NewVersions(VersioningOpts{Vendor: "kubuskotak", Default: "v1"})
+	versions.Handle(Version{Label: "v1", Number: "1.4.0"}, handler("orders-v1"))
+	versions.Handle(Version{Label: "v2", Number: "2.0.1"}, handler("orders-v2"))
+
+	r := chi.NewRouter()
+	r.Mount("/api", versions)
+	return r
+}
+
+func TestVersions(t *testing.T) {
+	cases := []struct {
+		name     string
+		path     string
+		header   http.Header
+		handler  string
+		version  Version
+		mimeType string
+	}{
+		{"path", "/api/v2/orders", nil, "orders-v2", Version{Label: "v2", Number: "2.0.1"}, MIMEApplicationJSONCharsetUTF8},
+		{"header", "/api/orders", http.Header{"Api-Version": {"2"}}, "orders-v2", Version{Label: "v2", Number: "2.0.1"}, MIMEApplicationJSONCharsetUTF8},
+		{"media type", "/api/orders", http.Header{HeaderAccept: {"application/vnd.kubuskotak.v2+json"}}, "orders-v2", Version{Label: "v2", Number: "2.0.1"}, MIMEApplicationJSONCharsetUTF8},
+		{"default", "/api/orders", nil, "orders-v1", Version{Label: "v1", Number: "1.4.0"}, MIMEApplicationJSONCharsetUTF8},
+		{"path over header", "/api/v1/orders", http.Header{"Api-Version": {"v2"}}, "orders-v1", Version{Label: "v1", Number: "1.4.0"}, MIMEApplicationJSONCharsetUTF8},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			versionedRouter().ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.mimeType, w.Header().Get(HeaderContentType))
+			var actual struct {
+				Version Version                `json:"version"`
+				Data    map[string]interface{} `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+			assert.Equal(t, tt.version, actual.Version)
+			assert.Equal(t, tt.handler, actual.Data["handler"])
+		})
+	}
+}
+
+func TestVersionsUnsupported(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		path   string
+		header http.Header
+		field  string
+	}{
+		{"path", "/api/v9/orders", nil, BindPath},
+		{"header", "/api/orders", http.Header{"Api-Version": {"3"}}, BindHeader},
+		{"media type", "/api/orders", http.Header{HeaderAccept: {"application/vnd.kubuskotak.v7+json"}}, BindHeader},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			versionedRouter().ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var actual Response
+			actual.Meta = &Meta{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+			meta := actual.Meta.(*Meta)
+			assert.Equal(t, "unsupported_version", meta.ErrorCode)
+			assert.Equal(t, tt.field, meta.Details[0].Field)
+		})
+	}
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	assert.Equal(t, "v2", normalizeVersion(" 2 "))
+	assert.Equal(t, "v2.1", normalizeVersion("V2.1"))
+	assert.Equal(t, "", normalizeVersion(""))
+}